@@ -8,12 +8,14 @@ require (
 	github.com/google/go-cmp v0.6.0
 	github.com/sirupsen/logrus v1.9.3
 	github.com/urfave/cli/v3 v3.0.0-alpha9
+	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673
 	go.xrstf.de/rudi v0.7.1-0.20240201200935-90d797505ff2
 	go.xrstf.de/rudi-contrib/set v0.1.1
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.14.0
 	k8s.io/apimachinery v0.29.0
 )
 
 require (
-	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 )
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package bayes
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/test"
+)
+
+func message(from, subject, body string) *email.Message {
+	return test.NewMessageBuilder().
+		WithFrom(from).
+		WithSubject(subject).
+		WithRawHeader("Date", "Sat, 17 Feb 2024 15:20:18 +0000").
+		WithBody(body).
+		Build()
+}
+
+var (
+	spamMessages = []*email.Message{
+		message("winner@lottery.example", "You have WON", "Claim your prize money now, click here to receive your lottery winnings."),
+		message("pharmacy@meds.example", "Cheap pills", "Buy cheap pills online, no prescription needed, click here for discount."),
+		message("offers@casino.example", "Free casino bonus", "Free bonus money at our online casino, click here to claim your prize."),
+	}
+
+	hamMessages = []*email.Message{
+		message("alice@example.com", "Meeting tomorrow", "Hi, can we move our meeting tomorrow to 3pm? The agenda is attached."),
+		message("bob@example.com", "Re: project status", "The project status report is ready, please review the agenda for the meeting."),
+		message("notifications@github.com", "[repo] New issue", "A new issue was opened in the repository, please review the pull request."),
+	}
+)
+
+func TestTokenize(t *testing.T) {
+	msg := message("Alice <alice@example.com>", "Meeting tomorrow", "Hi Bob, the meeting at 10 is moved. It's fine!")
+	tokens := Tokenize(msg)
+
+	for _, expected := range []string{"subject:meeting", "from:alice", "from:addr:alice@example.com", "from:domain:example.com", "meeting", "moved", "it's"} {
+		found := false
+		for _, token := range tokens {
+			if token == expected {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("Expected token %q in %v.", expected, tokens)
+		}
+	}
+
+	for _, token := range tokens {
+		if token == "10" || token == "hi" {
+			t.Errorf("Did not expect token %q.", token)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bayes.json")
+
+	// an untrained database cannot decide
+	db, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+
+	if result := db.Classify(Tokenize(spamMessages[0])); result.Score != 0.5 {
+		t.Fatalf("Expected untrained score of 0.5, got %v.", result.Score)
+	}
+
+	err = Update(filename, func(db *DB) error {
+		for _, msg := range spamMessages {
+			db.Train(Tokenize(msg), true)
+		}
+
+		for _, msg := range hamMessages {
+			db.Train(Tokenize(msg), false)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to train: %v", err)
+	}
+
+	db, err = Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+
+	if db.Spam != 3 || db.Ham != 3 {
+		t.Fatalf("Expected 3 spam and 3 ham messages, got %d and %d.", db.Spam, db.Ham)
+	}
+
+	spam := db.Classify(Tokenize(message("prize@lottery.example", "Claim your prize", "Click here to claim your lottery prize money.")))
+	if spam.Score < 0.9 {
+		t.Errorf("Expected spam score > 0.9, got %v (%v).", spam.Score, spam.Clues)
+	}
+
+	ham := db.Classify(Tokenize(message("carol@example.com", "Agenda for the meeting", "Please review the agenda for tomorrow's meeting.")))
+	if ham.Score > 0.1 {
+		t.Errorf("Expected ham score < 0.1, got %v (%v).", ham.Score, ham.Clues)
+	}
+
+	// untraining everything must leave an empty database
+	err = Update(filename, func(db *DB) error {
+		for _, msg := range spamMessages {
+			db.Untrain(Tokenize(msg), true)
+		}
+
+		for _, msg := range hamMessages {
+			db.Untrain(Tokenize(msg), false)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to untrain: %v", err)
+	}
+
+	db, err = Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load database: %v", err)
+	}
+
+	if db.Spam != 0 || db.Ham != 0 || len(db.Tokens) != 0 {
+		t.Fatalf("Expected empty database, got %d spam, %d ham and %d tokens.", db.Spam, db.Ham, len(db.Tokens))
+	}
+}
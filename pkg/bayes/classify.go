@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package bayes
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// strength and assumed are Robinson's s and x, i.e. how strongly the
+	// assumed probability of unknown tokens is weighed.
+	strength = 1.0
+	assumed  = 0.5
+	// minDeviation ignores tokens that are neither spammy nor hammy.
+	minDeviation = 0.1
+	// maxClues is the number of most significant tokens used for scoring.
+	maxClues = 150
+)
+
+// Clue is a token that was used to classify a message.
+type Clue struct {
+	Token       string  `json:"token"`
+	Probability float64 `json:"probability"`
+}
+
+// Result is the outcome of a classification.
+type Result struct {
+	// Score is the spam probability, from 0 (ham) to 1 (spam). Messages
+	// that cannot be classified (e.g. because the database has not been
+	// trained yet) have a score of 0.5.
+	Score float64
+	// Clues are the tokens used to calculate the score, the most
+	// significant first.
+	Clues []Clue
+}
+
+// Classify scores a message's tokens using Robinson's token probabilities,
+// combined with Fisher's method (as popularized by SpamBayes).
+func (db *DB) Classify(tokens []string) Result {
+	result := Result{Score: 0.5}
+
+	if db.Spam == 0 || db.Ham == 0 {
+		return result
+	}
+
+	var clues []Clue
+
+	for _, token := range tokens {
+		counts, ok := db.Tokens[token]
+		if !ok {
+			continue
+		}
+
+		probability := db.tokenProbability(counts)
+		if math.Abs(probability-0.5) < minDeviation {
+			continue
+		}
+
+		clues = append(clues, Clue{
+			Token:       token,
+			Probability: probability,
+		})
+	}
+
+	if len(clues) == 0 {
+		return result
+	}
+
+	sort.SliceStable(clues, func(i, j int) bool {
+		return math.Abs(clues[i].Probability-0.5) > math.Abs(clues[j].Probability-0.5)
+	})
+
+	if len(clues) > maxClues {
+		clues = clues[:maxClues]
+	}
+
+	var hamminess, spamminess float64
+	for _, clue := range clues {
+		hamminess += math.Log(clue.Probability)
+		spamminess += math.Log(1 - clue.Probability)
+	}
+
+	n := 2 * len(clues)
+	s := 1 - chi2Q(-2*spamminess, n)
+	h := 1 - chi2Q(-2*hamminess, n)
+
+	result.Score = (s - h + 1) / 2
+	result.Clues = clues
+
+	return result
+}
+
+func (db *DB) tokenProbability(counts Counts) float64 {
+	spamRatio := float64(counts.Spam) / float64(db.Spam)
+	hamRatio := float64(counts.Ham) / float64(db.Ham)
+
+	p := spamRatio / (spamRatio + hamRatio)
+	n := float64(counts.Spam + counts.Ham)
+
+	return (strength*assumed + n*p) / (strength + n)
+}
+
+// chi2Q is the inverse chi-square function, i.e. the probability that a
+// chi-square value this large happens by chance, for even degrees of freedom.
+func chi2Q(x2 float64, v int) float64 {
+	m := x2 / 2
+	sum := math.Exp(-m)
+	term := sum
+
+	for i := 1; i < v/2; i++ {
+		term *= m / float64(i)
+		sum += term
+	}
+
+	return math.Min(sum, 1)
+}
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package bayes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+// Counts is how often a token has been seen in spam and ham messages.
+type Counts struct {
+	Spam int `json:"s,omitempty"`
+	Ham  int `json:"h,omitempty"`
+}
+
+// DB is the token database of the classifier.
+type DB struct {
+	// Spam and Ham are the number of trained messages.
+	Spam   int               `json:"spam"`
+	Ham    int               `json:"ham"`
+	Tokens map[string]Counts `json:"tokens"`
+}
+
+func NewDB() *DB {
+	return &DB{
+		Tokens: map[string]Counts{},
+	}
+}
+
+// Train adds the tokens of a single message to the database.
+func (db *DB) Train(tokens []string, spam bool) {
+	if spam {
+		db.Spam++
+	} else {
+		db.Ham++
+	}
+
+	for _, token := range tokens {
+		counts := db.Tokens[token]
+
+		if spam {
+			counts.Spam++
+		} else {
+			counts.Ham++
+		}
+
+		db.Tokens[token] = counts
+	}
+}
+
+// Untrain removes the tokens of a message that was previously trained, e.g.
+// when the user corrects a wrong classification.
+func (db *DB) Untrain(tokens []string, spam bool) {
+	if spam && db.Spam > 0 {
+		db.Spam--
+	} else if !spam && db.Ham > 0 {
+		db.Ham--
+	}
+
+	for _, token := range tokens {
+		counts, ok := db.Tokens[token]
+		if !ok {
+			continue
+		}
+
+		if spam && counts.Spam > 0 {
+			counts.Spam--
+		} else if !spam && counts.Ham > 0 {
+			counts.Ham--
+		}
+
+		if counts.Spam == 0 && counts.Ham == 0 {
+			delete(db.Tokens, token)
+		} else {
+			db.Tokens[token] = counts
+		}
+	}
+}
+
+type cachedDB struct {
+	modified int64
+	db       *DB
+}
+
+var (
+	cacheLock sync.Mutex
+	cache     = map[string]cachedDB{}
+)
+
+// Load reads the database from a file. A missing file results in an empty
+// database. Databases are cached until the file is modified, so they must
+// not be modified by the caller; use Update instead.
+func Load(filename string) (*DB, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return NewDB(), nil
+		}
+
+		return nil, err
+	}
+
+	modified := info.ModTime().UnixNano()
+
+	cacheLock.Lock()
+	cached, ok := cache[filename]
+	cacheLock.Unlock()
+
+	if ok && cached.modified == modified {
+		return cached.db, nil
+	}
+
+	db, err := load(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheLock.Lock()
+	cache[filename] = cachedDB{
+		modified: modified,
+		db:       db,
+	}
+	cacheLock.Unlock()
+
+	return db, nil
+}
+
+func load(filename string) (*DB, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return NewDB(), nil
+		}
+
+		return nil, err
+	}
+
+	db := NewDB()
+	if err := json.Unmarshal(content, db); err != nil {
+		return nil, fmt.Errorf("invalid token database: %w", err)
+	}
+
+	if db.Tokens == nil {
+		db.Tokens = map[string]Counts{}
+	}
+
+	return db, nil
+}
+
+// Update loads the database, calls the mutate function and saves the result.
+// It is safe to be used by concurrent processes.
+func Update(filename string, mutate func(db *DB) error) error {
+	unlock, err := fs.LockFile(filename + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock token database: %w", err)
+	}
+	defer unlock()
+
+	db, err := load(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := mutate(db); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(db)
+	if err != nil {
+		return fmt.Errorf("failed to encode token database: %w", err)
+	}
+
+	return fs.WriteFileAtomic(filename, encoded)
+}
+
+// Filename returns the location of the token database inside the datadir.
+func Filename(datadir string) string {
+	return filepath.Join(datadir, "bayes.json")
+}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package bayes
+
+import (
+	"bytes"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/cases"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+const (
+	minTokenLength = 3
+	maxTokenLength = 24
+	// maxTokens limits how many (unique) tokens are taken from a message.
+	maxTokens = 2000
+)
+
+// tokenHeaders are the headers whose values are tokenized as a whole.
+var tokenHeaders = []string{
+	"Content-Type",
+	"List-Id",
+	"Precedence",
+	"X-Mailer",
+}
+
+// Tokenize returns the unique tokens of a message. Tokens from the subject
+// and headers are prefixed, so that e.g. "free" in the subject is a different
+// token than "free" in the body.
+func Tokenize(msg *email.Message) []string {
+	tokens := map[string]struct{}{}
+
+	add := func(prefix string, text string) {
+		for _, word := range words(text) {
+			if len(tokens) >= maxTokens {
+				return
+			}
+
+			tokens[prefix+word] = struct{}{}
+		}
+	}
+
+	add("subject:", msg.GetSubject())
+
+	addresses := map[string]*mail.Address{
+		"from":    msg.GetFrom(),
+		"replyto": msg.GetReplyTo(),
+	}
+
+	for prefix, addr := range addresses {
+		if addr == nil {
+			continue
+		}
+
+		add(prefix+":", addr.Name)
+
+		address := strings.ToLower(addr.Address)
+		tokens[prefix+":addr:"+address] = struct{}{}
+
+		if idx := strings.LastIndex(address, "@"); idx >= 0 {
+			tokens[prefix+":domain:"+address[idx+1:]] = struct{}{}
+		}
+	}
+
+	for _, header := range tokenHeaders {
+		if value := msg.Header.Get(header); value != "" {
+			// only the media type, parameters like boundaries are random
+			value, _, _ = strings.Cut(value, ";")
+			tokens["header:"+strings.ToLower(header)+":"+strings.ToLower(strings.TrimSpace(value))] = struct{}{}
+		}
+	}
+
+	if urls, err := msg.URLs(); err == nil {
+		for _, u := range urls {
+			tokens["url:"+u.Domain] = struct{}{}
+		}
+	}
+
+	if parts, err := msg.Parts(); err == nil {
+		for _, part := range parts {
+			switch part.ContentType {
+			case "text/plain":
+				add("", string(part.Body))
+			case "text/html":
+				add("", htmlText(part.Body))
+			default:
+				tokens["part:"+part.ContentType] = struct{}{}
+			}
+		}
+	} else {
+		add("", msg.Body)
+	}
+
+	result := make([]string, 0, len(tokens))
+	for token := range tokens {
+		result = append(result, token)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// words splits text into case-folded words, skipping very short or long
+// words and pure numbers.
+func words(text string) []string {
+	var result []string
+
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '$' && r != '€' && r != '\'' && r != '-'
+	})
+
+	for _, field := range fields {
+		field = strings.Trim(field, "'-")
+
+		length := len([]rune(field))
+		if length < minTokenLength || length > maxTokenLength {
+			continue
+		}
+
+		if strings.IndexFunc(field, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+
+		result = append(result, cases.Fold().String(field))
+	}
+
+	return result
+}
+
+// htmlText returns the visible text of an HTML document.
+func htmlText(body []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	var (
+		buf  strings.Builder
+		skip int
+	)
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// io.EOF or a broken document, either way we are done
+			return buf.String()
+
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "style" || string(name) == "script" {
+				skip++
+			}
+
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); (string(name) == "style" || string(name) == "script") && skip > 0 {
+				skip--
+			}
+
+		case html.TextToken:
+			if skip == 0 {
+				buf.Write(tokenizer.Text())
+				buf.WriteString(" ")
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package classify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.xrstf.de/rudi-lda/pkg/bayes"
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+func action(_ context.Context, opt *Options) error {
+	// read data from stdin
+	rawMail, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read from stdin: %w", err)
+	}
+
+	// parse email
+	msg, err := email.ParseMessage(rawMail)
+	if err != nil {
+		return fmt.Errorf("failed to parse mail body: %w", err)
+	}
+
+	db, err := bayes.Load(bayes.Filename(opt.DataDir))
+	if err != nil {
+		return fmt.Errorf("failed to load classifier: %w", err)
+	}
+
+	result := db.Classify(bayes.Tokenize(msg))
+
+	fmt.Printf("score: %.4f\n", result.Score)
+	fmt.Printf("trained: %d spam, %d ham\n", db.Spam, db.Ham)
+
+	for i, clue := range result.Clues {
+		if int64(i) >= opt.Clues {
+			break
+		}
+
+		fmt.Printf("  %.4f  %s\n", clue.Probability, clue.Token)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package classify
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir string
+	Clues   int64
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "classify",
+		Usage:           "prints the spam probability of the e-mail on stdin",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+			&cli.IntFlag{
+				Name:        "clues",
+				Usage:       "number of most significant tokens to print",
+				Value:       10,
+				Destination: &opt.Clues,
+			},
+		},
+		Action: func(ctx context.Context, _ *cli.Command) error {
+			return action(ctx, opt)
+		},
+	}
+}
@@ -9,9 +9,19 @@ import (
 
 	"github.com/urfave/cli/v3"
 
+	"go.xrstf.de/rudi-lda/pkg/commandline/classify"
 	"go.xrstf.de/rudi-lda/pkg/commandline/deliver"
+	"go.xrstf.de/rudi-lda/pkg/commandline/kv"
+	"go.xrstf.de/rudi-lda/pkg/commandline/learn"
+	"go.xrstf.de/rudi-lda/pkg/commandline/list"
+	"go.xrstf.de/rudi-lda/pkg/commandline/mute"
 	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+	"go.xrstf.de/rudi-lda/pkg/commandline/quarantine"
+	"go.xrstf.de/rudi-lda/pkg/commandline/repl"
+	"go.xrstf.de/rudi-lda/pkg/commandline/screener"
 	"go.xrstf.de/rudi-lda/pkg/commandline/spamtest"
+	"go.xrstf.de/rudi-lda/pkg/commandline/test"
+	"go.xrstf.de/rudi-lda/pkg/commandline/train"
 )
 
 func newVersionPrinter(buildTag, buildCommit, buildDate string) func(cmd *cli.Command) {
@@ -50,7 +60,18 @@ func New(buildTag, buildCommit, buildDate string) *cli.Command {
 		Version: version,
 		Commands: []*cli.Command{
 			deliver.Command(opt),
+			deliver.ExplainCommand(opt),
 			spamtest.Command(opt),
+			test.Command(opt),
+			repl.Command(opt),
+			mute.Command(opt),
+			list.Command(opt),
+			kv.Command(opt),
+			train.Command(opt),
+			classify.Command(opt),
+			learn.Command(opt),
+			quarantine.Command(opt),
+			screener.Command(opt),
 		},
 	}
 }
@@ -5,6 +5,7 @@ package deliver
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -13,23 +14,43 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"go.xrstf.de/rudi-lda/pkg/dnsbl"
 	"go.xrstf.de/rudi-lda/pkg/email"
 	"go.xrstf.de/rudi-lda/pkg/fs"
+	"go.xrstf.de/rudi-lda/pkg/kv"
 	"go.xrstf.de/rudi-lda/pkg/log"
+	maildirs "go.xrstf.de/rudi-lda/pkg/maildir"
 	"go.xrstf.de/rudi-lda/pkg/metrics"
 	"go.xrstf.de/rudi-lda/pkg/processor"
 	"go.xrstf.de/rudi-lda/pkg/processor/antispam"
+	"go.xrstf.de/rudi-lda/pkg/processor/bounces"
+	"go.xrstf.de/rudi-lda/pkg/processor/extractor"
 	"go.xrstf.de/rudi-lda/pkg/processor/ldaheaders"
 	"go.xrstf.de/rudi-lda/pkg/processor/maildir"
 	"go.xrstf.de/rudi-lda/pkg/processor/rentablo"
+	"go.xrstf.de/rudi-lda/pkg/processor/rewrite"
 	"go.xrstf.de/rudi-lda/pkg/processor/sunnyportal"
+	"go.xrstf.de/rudi-lda/pkg/quarantine"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+	"go.xrstf.de/rudi-lda/pkg/screener"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+	"go.xrstf.de/rudi-lda/pkg/thread"
 )
 
 func action(ctx context.Context, opt *Options) error {
+	if opt.DryRun {
+		return dryRun(ctx, opt)
+	}
+
 	if err := log.SetDirectory(opt.DataDir); err != nil {
 		return fmt.Errorf("invalid --datadir: %w", err)
 	}
 
+	rudilib.SetDataDirectory(opt.DataDir)
+	rudilib.SetDNSBLChecker(getDNSBLChecker(opt, filepath.Join(opt.DataDir, "dnsbl.json")))
+	rudilib.SetKVStore(kv.New(kv.Filename(opt.DataDir)))
+	rudilib.SetLimits(getScriptLimits(opt))
+
 	// read data from stdin
 	rawMail, err := io.ReadAll(os.Stdin)
 	if err != nil {
@@ -37,7 +58,8 @@ func action(ctx context.Context, opt *Options) error {
 	}
 
 	// setup logger
-	var logger logrus.FieldLogger = log.New("mails.log")
+	baseLogger := log.New("mails.log")
+	var logger logrus.FieldLogger = baseLogger
 
 	// init metrics
 	metricsFile := filepath.Join(opt.DataDir, "metrics.json")
@@ -64,7 +86,25 @@ func action(ctx context.Context, opt *Options) error {
 
 	// process it
 	logger = logger.WithFields(msg.LogFields()).WithField("destination", opt.DestUser)
-	processors := getProcessors(opt)
+
+	if traceSender(opt, msg) {
+		baseLogger.SetLevel(logrus.DebugLevel)
+
+		ctx = rudilib.WithTracer(ctx, rudilib.NewTracer(func(call rudilib.Call) {
+			logger.WithField("script", call.Script).
+				WithField("depth", call.Depth).
+				WithField("result", call.Result).
+				WithField("error", call.Error).
+				Debugf("Called %s.", call.Expression)
+		}))
+	}
+
+	processors, err := getProcessors(opt)
+	if err != nil {
+		// do not lose the e-mail just because of a broken configuration
+		logger.WithError(err).Error("Invalid configuration, delivering into inbox.")
+		processors = []processor.Processor{maildir.New(getDestinationMaildir(opt), "")}
+	}
 
 	if newMsg, err := processor.Pipeline(ctx, logger, processors, msg, metricsData); err != nil {
 		logger.WithError(err).Error("E-mail is unprocessable")
@@ -78,7 +118,65 @@ func action(ctx context.Context, opt *Options) error {
 	return nil
 }
 
-func getProcessors(opt *Options) []processor.Processor {
+// dryRun runs the e-mail through the pipeline without any side effects
+// (no deliveries, data files, metrics or logs) and prints the trace.
+func dryRun(ctx context.Context, opt *Options) error {
+	if opt.Output != "text" && opt.Output != "json" {
+		return fmt.Errorf("invalid --output %q, must be text or json", opt.Output)
+	}
+
+	rudilib.SetDataDirectory(opt.DataDir)
+	rudilib.SetDNSBLChecker(getDNSBLChecker(opt, ""))
+	rudilib.SetKVStore(kv.New(kv.Filename(opt.DataDir)).DryRun())
+	rudilib.SetLimits(getScriptLimits(opt))
+
+	rawMail, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read from stdin: %w", err)
+	}
+
+	msg, err := email.ParseMessage(rawMail)
+	if err != nil {
+		return fmt.Errorf("failed to parse mail body: %w", err)
+	}
+
+	processors, err := getProcessors(opt)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// only warnings and errors are interesting, the rest is in the trace
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	metricsData, _ := metrics.Load("")
+
+	trace := &processor.Trace{}
+	ctx = processor.WithTrace(ctx, trace)
+	ctx = processor.WithEffects(ctx, processor.NewDryRun(trace))
+
+	tracer := rudilib.NewTracer(func(call rudilib.Call) {
+		processor.Notef(ctx, "%s", call)
+	})
+	ctx = rudilib.WithTracer(ctx, tracer)
+
+	if _, err := processor.Pipeline(ctx, logger, processors, msg, metricsData); err != nil {
+		logger.WithError(err).Error("E-mail is unprocessable")
+	}
+
+	if opt.Output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(trace)
+	}
+
+	trace.Print(os.Stdout)
+
+	return nil
+}
+
+func getProcessors(opt *Options) ([]processor.Processor, error) {
 	// assemble the path to the destination user's maildir
 	userMaildir := getDestinationMaildir(opt)
 
@@ -87,6 +185,10 @@ func getProcessors(opt *Options) []processor.Processor {
 	// add common headers
 	processors = append(processors, ldaheaders.New(opt.DestUser))
 
+	if opt.RewriteScript != "" {
+		processors = append(processors, rewrite.New(opt.RewriteScript))
+	}
+
 	if opt.Rentablo {
 		processors = append(processors, rentablo.New(opt.DataDir))
 	}
@@ -95,23 +197,102 @@ func getProcessors(opt *Options) []processor.Processor {
 		processors = append(processors, sunnyportal.New(opt.DataDir))
 	}
 
+	if opt.ExtractorRules != "" {
+		rules, err := extractor.LoadRules(opt.ExtractorRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load extractor rules: %w", err)
+		}
+
+		processors = append(processors, extractor.New(opt.DataDir, rules))
+	}
+
+	if opt.Bounces {
+		processors = append(processors, bounces.New(opt.DataDir, opt.DestUser))
+	}
+
+	policy, err := spam.NewPolicy(opt.SpamActions, opt.MaybeSpamActions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spam actions: %w", err)
+	}
+
 	if opt.SpamScript != "" {
-		var backupDir string
-		if opt.BackupSpam {
-			backupDir = filepath.Join(opt.DataDir, "spam")
+		thresholds, err := spam.NewThresholds(opt.MaybeSpamThreshold, opt.SpamThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spam thresholds: %w", err)
+		}
+
+		antispamProc := antispam.New(opt.SpamScript).WithThresholds(thresholds).WithPolicy(policy, opt.SpamTag)
+
+		if opt.Quarantine {
+			antispamProc.WithQuarantine(quarantine.New(quarantine.Directory(opt.DataDir), opt.QuarantineRetention), opt.DestUser)
 		}
 
-		processors = append(processors, antispam.New(opt.SpamScript, backupDir))
+		processors = append(processors, antispamProc)
 	}
 
 	// maildir will always consume any e-mail
-	processors = append(processors, maildir.New(userMaildir, opt.FolderScript))
+	maildirProc := maildir.New(userMaildir, opt.FolderScript)
+
+	if opt.SpamScript != "" {
+		maildirProc.WithSpamPolicy(policy, opt.JunkFolder)
+	}
+
+	if opt.Threads || opt.FollowThreads || opt.Mute {
+		index := thread.New(filepath.Join(opt.DataDir, "threads.json"), opt.ThreadRetention)
+		maildirProc.WithThreads(index, opt.FollowThreads)
 
-	return processors
+		if opt.Mute {
+			maildirProc.WithMuting(opt.MutedFolder, opt.ArchiveFolder)
+		}
+	}
+
+	if opt.Screener {
+		q := quarantine.New(quarantine.Directory(opt.DataDir), opt.QuarantineRetention)
+		maildirProc.WithScreener(screener.New(opt.DataDir), opt.ScreenerFolder, q, opt.DestUser)
+	}
+
+	processors = append(processors, maildirProc)
+
+	return processors, nil
+}
+
+// traceSender returns true if the script calls for the message should be
+// logged. Senders can be given as full addresses or as "@domain".
+func traceSender(opt *Options, msg *email.Message) bool {
+	from := msg.GetFrom()
+	if from == nil {
+		return false
+	}
+
+	address := strings.ToLower(from.Address)
+
+	for _, sender := range opt.TraceSenders {
+		sender = strings.ToLower(sender)
+
+		if address == sender || (strings.HasPrefix(sender, "@") && strings.HasSuffix(address, sender)) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func getDestinationMaildir(opt *Options) string {
-	parts := strings.Split(opt.DestUser, "@")
+	return maildirs.UserDirectory(opt.MailDir, opt.DestUser)
+}
+
+func getScriptLimits(opt *Options) rudilib.Limits {
+	return rudilib.Limits{
+		Timeout:         opt.ScriptTimeout,
+		MaxSteps:        int(opt.ScriptMaxSteps),
+		MaxDepth:        int(opt.ScriptMaxDepth),
+		UnsafeFunctions: opt.UnsafeFunctions,
+	}
+}
 
-	return filepath.Join(opt.MailDir, parts[0])
+func getDNSBLChecker(opt *Options, cacheFile string) *dnsbl.Checker {
+	return dnsbl.
+		NewChecker(dnsbl.NewResolver(opt.DNSServer), cacheFile, opt.DNSCacheTTL).
+		WithTimeout(opt.DNSTimeout).
+		WithBudget(int(opt.DNSBudget))
 }
@@ -5,24 +5,66 @@ package deliver
 
 import (
 	"context"
+	"time"
 
 	"github.com/urfave/cli/v3"
 
 	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+	"go.xrstf.de/rudi-lda/pkg/dnsbl"
+	"go.xrstf.de/rudi-lda/pkg/processor/antispam"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+	"go.xrstf.de/rudi-lda/pkg/screener"
+	"go.xrstf.de/rudi-lda/pkg/spam"
 )
 
 type Options struct {
 	Common *options.CommonOptions
 
-	FromAddress  string
-	DestUser     string
-	SpamScript   string
-	FolderScript string
-	BackupSpam   bool
-	MailDir      string
-	DataDir      string
-	Rentablo     bool
-	Sunnyportal  bool
+	FromAddress    string
+	DestUser       string
+	RewriteScript  string
+	SpamScript     string
+	FolderScript   string
+	Quarantine     bool
+	MailDir        string
+	DataDir        string
+	Rentablo       bool
+	Sunnyportal    bool
+	Bounces        bool
+	ExtractorRules string
+
+	Threads         bool
+	FollowThreads   bool
+	ThreadRetention time.Duration
+	Mute            bool
+	MutedFolder     string
+	ArchiveFolder   string
+
+	Screener       bool
+	ScreenerFolder string
+
+	DNSServer   string
+	DNSTimeout  time.Duration
+	DNSBudget   int64
+	DNSCacheTTL time.Duration
+
+	MaybeSpamThreshold float64
+	SpamThreshold      float64
+	SpamActions        []string
+	MaybeSpamActions   []string
+	JunkFolder         string
+	SpamTag            string
+
+	QuarantineRetention time.Duration
+
+	ScriptTimeout   time.Duration
+	ScriptMaxSteps  int64
+	ScriptMaxDepth  int64
+	UnsafeFunctions []string
+	TraceSenders    []string
+
+	DryRun bool
+	Output string
 }
 
 func Command(commonOpt *options.CommonOptions) *cli.Command {
@@ -34,67 +76,281 @@ func Command(commonOpt *options.CommonOptions) *cli.Command {
 		Name:            "deliver",
 		Usage:           "delivers e-mail into a Maildir++ folder (default command)",
 		HideHelpCommand: true,
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:        "maildir",
-				Usage:       "(required) path to the root of the user's Maildir directory",
-				Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
-				Destination: &opt.MailDir,
-				Required:    true,
-			},
-			&cli.StringFlag{
-				Name:        "datadir",
-				Usage:       "(required) path to where metrics and other data files should be placed",
-				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
-				Destination: &opt.DataDir,
-				Required:    true,
-			},
-			&cli.StringFlag{
-				Name:        "from",
-				Aliases:     []string{"f"},
-				Usage:       "from address",
-				Destination: &opt.FromAddress,
-			},
-			&cli.StringFlag{
-				Name:        "destination",
-				Aliases:     []string{"d"},
-				Usage:       "(required) destination user",
-				Destination: &opt.DestUser,
-				Required:    true,
-			},
-			&cli.StringFlag{
-				Name:        "spam-script",
-				Usage:       "Rudi script that will be evaluated to determine if the incoming e-mail is spam",
-				Sources:     cli.EnvVars("RUDILDA_SPAM_SCRIPT"),
-				Destination: &opt.SpamScript,
-			},
-			&cli.StringFlag{
-				Name:        "folder-script",
-				Usage:       "Rudi script that will be evaluated to determine the target folder for an incoming e-mail",
-				Sources:     cli.EnvVars("RUDILDA_FOLDER_SCRIPT"),
-				Destination: &opt.FolderScript,
-			},
-			&cli.BoolFlag{
-				Name:        "rentablo",
-				Usage:       "enable the rentablo.de processor",
-				Sources:     cli.EnvVars("RUDILDA_RENTABLO"),
-				Destination: &opt.Rentablo,
-			},
-			&cli.BoolFlag{
-				Name:        "sunnyportal",
-				Usage:       "enable the sunnyportal.de processor",
-				Sources:     cli.EnvVars("RUDILDA_SUNNYPORTAL"),
-				Destination: &opt.Sunnyportal,
-			},
+		Flags: append(flags(opt),
 			&cli.BoolFlag{
-				Name:        "backup-spam",
-				Usage:       "write spam e-mails to $datadir/spam",
-				Sources:     cli.EnvVars("RUDILDA_BACKUP_SPAM"),
-				Destination: &opt.BackupSpam,
+				Name:        "dry-run",
+				Usage:       "do not deliver the e-mail or change any files, but print what would happen (like explain)",
+				Destination: &opt.DryRun,
 			},
+		),
+		Action: func(ctx context.Context, _ *cli.Command) error {
+			return action(ctx, opt)
 		},
+	}
+}
+
+// ExplainCommand is the same as deliver --dry-run.
+func ExplainCommand(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+		DryRun: true,
+	}
+
+	return &cli.Command{
+		Name:            "explain",
+		Usage:           "prints what each processor would do with the e-mail on stdin, without delivering it",
+		HideHelpCommand: true,
+		Flags:           flags(opt),
 		Action: func(ctx context.Context, _ *cli.Command) error {
 			return action(ctx, opt)
 		},
 	}
 }
+
+func flags(opt *Options) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "maildir",
+			Usage:       "(required) path to the root of the user's Maildir directory",
+			Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
+			Destination: &opt.MailDir,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "datadir",
+			Usage:       "(required) path to where metrics and other data files should be placed",
+			Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+			Destination: &opt.DataDir,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "from",
+			Aliases:     []string{"f"},
+			Usage:       "from address",
+			Destination: &opt.FromAddress,
+		},
+		&cli.StringFlag{
+			Name:        "destination",
+			Aliases:     []string{"d"},
+			Usage:       "(required) destination user",
+			Destination: &opt.DestUser,
+			Required:    true,
+		},
+		&cli.StringFlag{
+			Name:        "rewrite-script",
+			Usage:       "Rudi script that will be evaluated to modify the incoming e-mail before any other processing",
+			Sources:     cli.EnvVars("RUDILDA_REWRITE_SCRIPT"),
+			Destination: &opt.RewriteScript,
+		},
+		&cli.StringFlag{
+			Name:        "spam-script",
+			Usage:       "Rudi script that will be evaluated to determine if the incoming e-mail is spam",
+			Sources:     cli.EnvVars("RUDILDA_SPAM_SCRIPT"),
+			Destination: &opt.SpamScript,
+		},
+		&cli.FloatFlag{
+			Name:        "maybe-spam-threshold",
+			Usage:       "total score (see score!) at which e-mails are considered maybe-spam",
+			Value:       spam.DefaultThresholds.MaybeSpam,
+			Sources:     cli.EnvVars("RUDILDA_MAYBE_SPAM_THRESHOLD"),
+			Destination: &opt.MaybeSpamThreshold,
+		},
+		&cli.FloatFlag{
+			Name:        "spam-threshold",
+			Usage:       "total score (see score!) at which e-mails are considered spam",
+			Value:       spam.DefaultThresholds.Spam,
+			Sources:     cli.EnvVars("RUDILDA_SPAM_THRESHOLD"),
+			Destination: &opt.SpamThreshold,
+		},
+		&cli.StringSliceFlag{
+			Name:        "spam-action",
+			Usage:       "what to do with spam: discard, junk, tag or keyword (can be given multiple times)",
+			Value:       []string{string(spam.ActionDiscard)},
+			Sources:     cli.EnvVars("RUDILDA_SPAM_ACTION"),
+			Destination: &opt.SpamActions,
+		},
+		&cli.StringSliceFlag{
+			Name:        "maybe-spam-action",
+			Usage:       "what to do with maybe-spam: spam, junk, tag or keyword (can be given multiple times)",
+			Sources:     cli.EnvVars("RUDILDA_MAYBE_SPAM_ACTION"),
+			Destination: &opt.MaybeSpamActions,
+		},
+		&cli.StringFlag{
+			Name:        "junk-folder",
+			Usage:       "Maildir folder for the junk action",
+			Value:       "Junk",
+			Sources:     cli.EnvVars("RUDILDA_JUNK_FOLDER"),
+			Destination: &opt.JunkFolder,
+		},
+		&cli.StringFlag{
+			Name:        "spam-tag",
+			Usage:       "subject prefix for the tag action",
+			Value:       antispam.DefaultTag,
+			Sources:     cli.EnvVars("RUDILDA_SPAM_TAG"),
+			Destination: &opt.SpamTag,
+		},
+		&cli.StringFlag{
+			Name:        "folder-script",
+			Usage:       "Rudi script that will be evaluated to determine the target folder for an incoming e-mail",
+			Sources:     cli.EnvVars("RUDILDA_FOLDER_SCRIPT"),
+			Destination: &opt.FolderScript,
+		},
+		&cli.BoolFlag{
+			Name:        "rentablo",
+			Usage:       "enable the rentablo.de processor",
+			Sources:     cli.EnvVars("RUDILDA_RENTABLO"),
+			Destination: &opt.Rentablo,
+		},
+		&cli.BoolFlag{
+			Name:        "sunnyportal",
+			Usage:       "enable the sunnyportal.de processor",
+			Sources:     cli.EnvVars("RUDILDA_SUNNYPORTAL"),
+			Destination: &opt.Sunnyportal,
+		},
+		&cli.StringFlag{
+			Name:        "extractor-rules",
+			Usage:       "directory with JSON rule files for extracting data from e-mails into files in $datadir",
+			Sources:     cli.EnvVars("RUDILDA_EXTRACTOR_RULES"),
+			Destination: &opt.ExtractorRules,
+		},
+		&cli.BoolFlag{
+			Name:        "bounces",
+			Usage:       "log bounces (delivery status notifications) to $datadir/bounces.jsonl",
+			Sources:     cli.EnvVars("RUDILDA_BOUNCES"),
+			Destination: &opt.Bounces,
+		},
+		&cli.BoolFlag{
+			Name:        "quarantine",
+			Aliases:     []string{"backup-spam"},
+			Usage:       "keep discarded spam e-mails in the quarantine in $datadir/quarantine",
+			Sources:     cli.EnvVars("RUDILDA_QUARANTINE", "RUDILDA_BACKUP_SPAM"),
+			Destination: &opt.Quarantine,
+		},
+		&cli.DurationFlag{
+			Name:        "quarantine-retention",
+			Usage:       "how long to keep e-mails in the quarantine",
+			Value:       30 * 24 * time.Hour,
+			Sources:     cli.EnvVars("RUDILDA_QUARANTINE_RETENTION"),
+			Destination: &opt.QuarantineRetention,
+		},
+		&cli.BoolFlag{
+			Name:        "threads",
+			Usage:       "remember the folder of each delivered e-mail in $datadir/threads.json and expose it to the folder script as .thread",
+			Sources:     cli.EnvVars("RUDILDA_THREADS"),
+			Destination: &opt.Threads,
+		},
+		&cli.BoolFlag{
+			Name:        "follow-threads",
+			Usage:       "deliver replies into the folder of their thread unless the folder script decides otherwise (implies --threads)",
+			Sources:     cli.EnvVars("RUDILDA_FOLLOW_THREADS"),
+			Destination: &opt.FollowThreads,
+		},
+		&cli.DurationFlag{
+			Name:        "thread-retention",
+			Usage:       "how long to remember delivered e-mails in the thread index",
+			Value:       90 * 24 * time.Hour,
+			Sources:     cli.EnvVars("RUDILDA_THREAD_RETENTION"),
+			Destination: &opt.ThreadRetention,
+		},
+		&cli.BoolFlag{
+			Name:        "mute",
+			Usage:       "deliver follow-ups of muted threads as read into the archive folder (implies --threads)",
+			Sources:     cli.EnvVars("RUDILDA_MUTE"),
+			Destination: &opt.Mute,
+		},
+		&cli.StringFlag{
+			Name:        "muted-folder",
+			Usage:       "Maildir folder that mutes the threads of all e-mails moved into it",
+			Value:       "Muted",
+			Sources:     cli.EnvVars("RUDILDA_MUTED_FOLDER"),
+			Destination: &opt.MutedFolder,
+		},
+		&cli.StringFlag{
+			Name:        "archive-folder",
+			Usage:       "Maildir folder to deliver muted e-mails into",
+			Value:       "Archive",
+			Sources:     cli.EnvVars("RUDILDA_ARCHIVE_FOLDER"),
+			Destination: &opt.ArchiveFolder,
+		},
+		&cli.BoolFlag{
+			Name:        "screener",
+			Usage:       "deliver e-mails from unknown senders into the screener folder and quarantine e-mails from rejected senders",
+			Sources:     cli.EnvVars("RUDILDA_SCREENER"),
+			Destination: &opt.Screener,
+		},
+		&cli.StringFlag{
+			Name:        "screener-folder",
+			Usage:       "Maildir folder for e-mails from unknown senders; e-mails moved into its Accept/Reject subfolders accept/reject their senders",
+			Value:       screener.DefaultFolder,
+			Sources:     cli.EnvVars("RUDILDA_SCREENER_FOLDER"),
+			Destination: &opt.ScreenerFolder,
+		},
+		&cli.StringFlag{
+			Name:        "dns-server",
+			Usage:       "DNS server (host:port) to use for DNS blocklist lookups instead of the system resolver",
+			Sources:     cli.EnvVars("RUDILDA_DNS_SERVER"),
+			Destination: &opt.DNSServer,
+		},
+		&cli.DurationFlag{
+			Name:        "dns-timeout",
+			Usage:       "timeout for each DNS blocklist lookup",
+			Value:       dnsbl.DefaultTimeout,
+			Sources:     cli.EnvVars("RUDILDA_DNS_TIMEOUT"),
+			Destination: &opt.DNSTimeout,
+		},
+		&cli.IntFlag{
+			Name:        "dns-budget",
+			Usage:       "maximum number of DNS blocklist lookups per e-mail",
+			Value:       dnsbl.DefaultBudget,
+			Sources:     cli.EnvVars("RUDILDA_DNS_BUDGET"),
+			Destination: &opt.DNSBudget,
+		},
+		&cli.DurationFlag{
+			Name:        "dns-cache-ttl",
+			Usage:       "how long to cache DNS blocklist results",
+			Value:       time.Hour,
+			Sources:     cli.EnvVars("RUDILDA_DNS_CACHE_TTL"),
+			Destination: &opt.DNSCacheTTL,
+		},
+		&cli.DurationFlag{
+			Name:        "script-timeout",
+			Usage:       "maximum runtime of each script (0 disables the limit)",
+			Value:       rudilib.DefaultLimits.Timeout,
+			Sources:     cli.EnvVars("RUDILDA_SCRIPT_TIMEOUT"),
+			Destination: &opt.ScriptTimeout,
+		},
+		&cli.IntFlag{
+			Name:        "script-max-steps",
+			Usage:       "maximum number of function calls of each script (0 disables the limit)",
+			Value:       int64(rudilib.DefaultLimits.MaxSteps),
+			Sources:     cli.EnvVars("RUDILDA_SCRIPT_MAX_STEPS"),
+			Destination: &opt.ScriptMaxSteps,
+		},
+		&cli.IntFlag{
+			Name:        "script-max-depth",
+			Usage:       "maximum nesting depth of function calls in scripts (0 disables the limit)",
+			Value:       int64(rudilib.DefaultLimits.MaxDepth),
+			Sources:     cli.EnvVars("RUDILDA_SCRIPT_MAX_DEPTH"),
+			Destination: &opt.ScriptMaxDepth,
+		},
+		&cli.StringSliceFlag{
+			Name:        "unsafe-function",
+			Usage:       "allow scripts to use this unsafe built-in Rudi function, \"*\" allows all of them (can be given multiple times)",
+			Sources:     cli.EnvVars("RUDILDA_UNSAFE_FUNCTION"),
+			Destination: &opt.UnsafeFunctions,
+		},
+		&cli.StringSliceFlag{
+			Name:        "trace-sender",
+			Usage:       "log every function call made by scripts at debug level for e-mails from this address or @domain (can be given multiple times)",
+			Sources:     cli.EnvVars("RUDILDA_TRACE_SENDER"),
+			Destination: &opt.TraceSenders,
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "output format of --dry-run/explain: text or json",
+			Value:       "text",
+			Destination: &opt.Output,
+		},
+	}
+}
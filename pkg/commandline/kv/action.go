@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/kv"
+)
+
+func getStore(opt *Options) *kv.Store {
+	return kv.New(kv.Filename(opt.DataDir))
+}
+
+func listAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) > 1 {
+		return errors.New("at most one prefix can be given")
+	}
+
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	items, err := getStore(opt).List(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	for _, item := range items {
+		expires := "never"
+		if !item.Expires.IsZero() {
+			expires = item.Expires.Format(time.DateTime)
+		}
+
+		fmt.Printf("%s  %s  (updated %s, expires %s)\n",
+			item.Key,
+			encode(item.Value),
+			item.Updated.Format(time.DateTime),
+			expires,
+		)
+	}
+
+	return nil
+}
+
+func getAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one key must be given")
+	}
+
+	entry, err := getStore(opt).Get(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get key: %w", err)
+	}
+
+	if entry == nil {
+		fmt.Println("(not set)")
+		return nil
+	}
+
+	fmt.Println(encode(entry.Value))
+
+	return nil
+}
+
+func clearAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no keys given")
+	}
+
+	removed, err := getStore(opt).Delete(args...)
+	if err != nil {
+		return fmt.Errorf("failed to clear keys: %w", err)
+	}
+
+	for _, key := range removed {
+		fmt.Printf("removed %s\n", key)
+	}
+
+	return nil
+}
+
+func encode(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(encoded)
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package kv
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "kv",
+		Usage:           "inspects and clears the key-value state used by scripts",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:      "list",
+				Usage:     "lists all keys (optionally only those starting with the prefix) and their values",
+				ArgsUsage: "[PREFIX]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return listAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "prints the value of a key",
+				ArgsUsage: "KEY",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return getAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "clear",
+				Usage:     "removes keys; keys ending with \"*\" remove all keys with that prefix",
+				ArgsUsage: "KEY [KEY ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return clearAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+		},
+	}
+}
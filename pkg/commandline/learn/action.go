@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package learn
+
+import (
+	"context"
+	"fmt"
+
+	"go.xrstf.de/rudi-lda/pkg/learn"
+	"go.xrstf.de/rudi-lda/pkg/log"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+)
+
+func action(_ context.Context, opt *Options) error {
+	if err := log.SetDirectory(opt.DataDir); err != nil {
+		return fmt.Errorf("invalid --datadir: %w", err)
+	}
+
+	md, err := maildir.New(opt.MailDir)
+	if err != nil {
+		return fmt.Errorf("invalid --maildir: %w", err)
+	}
+
+	learner := learn.New(md, opt.DataDir, learn.Options{
+		JunkFolder:     opt.JunkFolder,
+		IgnoredFolders: opt.IgnoredFolders,
+		HamAge:         opt.HamAge,
+		Allowlist:      opt.Allowlist,
+		Blocklist:      opt.Blocklist,
+	})
+
+	logger := log.New("learn.log")
+
+	stats, err := learner.Run(logger)
+	if err != nil {
+		return fmt.Errorf("failed to learn: %w", err)
+	}
+
+	logger.WithField("spam", stats.Spam).
+		WithField("ham", stats.Ham).
+		WithField("relearned", stats.Relearned).
+		WithField("skipped", stats.Skipped).
+		Info("Learned from Maildir.")
+
+	fmt.Printf("learned %d spam, %d ham, %d relearned, %d skipped\n", stats.Spam, stats.Ham, stats.Relearned, stats.Skipped)
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package learn
+
+import (
+	"context"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	MailDir        string
+	DataDir        string
+	JunkFolder     string
+	IgnoredFolders []string
+	HamAge         time.Duration
+	Allowlist      string
+	Blocklist      string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "learn",
+		Usage:           "trains the spam classifier with the e-mails the user sorted into (or out of) the Junk folder",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "maildir",
+				Usage:       "(required) path to the root of the user's Maildir directory",
+				Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
+				Destination: &opt.MailDir,
+				Required:    true,
+			},
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+			&cli.StringFlag{
+				Name:        "junk-folder",
+				Usage:       "Maildir folder whose e-mails are learned as spam",
+				Value:       "Junk",
+				Destination: &opt.JunkFolder,
+			},
+			&cli.StringSliceFlag{
+				Name:        "ignore-folder",
+				Usage:       "Maildir folder to never learn from (can be given multiple times)",
+				Value:       []string{"Trash"},
+				Destination: &opt.IgnoredFolders,
+			},
+			&cli.DurationFlag{
+				Name:        "ham-age",
+				Usage:       "age after which e-mails in the inbox are learned as ham (0 to disable)",
+				Value:       14 * 24 * time.Hour,
+				Destination: &opt.HamAge,
+			},
+			&cli.StringFlag{
+				Name:        "allowlist",
+				Usage:       "name of a list file in $datadir/lists to add the senders of ham to",
+				Destination: &opt.Allowlist,
+			},
+			&cli.StringFlag{
+				Name:        "blocklist",
+				Usage:       "name of a list file in $datadir/lists to add the senders of spam to",
+				Destination: &opt.Blocklist,
+			},
+		},
+		Action: func(ctx context.Context, _ *cli.Command) error {
+			return action(ctx, opt)
+		},
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package list
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.xrstf.de/rudi-lda/pkg/lists"
+)
+
+func addAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) < 2 {
+		return errors.New("no list name or entries given")
+	}
+
+	filename, err := lists.Filename(lists.Directory(opt.DataDir), args[0])
+	if err != nil {
+		return err
+	}
+
+	added, err := lists.Add(filename, args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to update list: %w", err)
+	}
+
+	for _, entry := range added {
+		fmt.Printf("added %s\n", entry)
+	}
+
+	return nil
+}
+
+func removeAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) < 2 {
+		return errors.New("no list name or entries given")
+	}
+
+	filename, err := lists.Find(args[0], lists.Directory(opt.DataDir))
+	if err != nil {
+		return err
+	}
+
+	removed, err := lists.Remove(filename, args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to update list: %w", err)
+	}
+
+	for _, entry := range removed {
+		fmt.Printf("removed %s\n", entry)
+	}
+
+	return nil
+}
+
+func showAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected exactly one list name")
+	}
+
+	list, err := loadList(opt, args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range list.Entries() {
+		fmt.Println(entry)
+	}
+
+	return nil
+}
+
+func checkAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) != 2 {
+		return errors.New("expected a list name and a value")
+	}
+
+	list, err := loadList(opt, args[0])
+	if err != nil {
+		return err
+	}
+
+	entry, ok := list.Match(args[1])
+	if !ok {
+		fmt.Println("(no match)")
+		return nil
+	}
+
+	fmt.Println(entry)
+
+	return nil
+}
+
+func loadList(opt *Options, name string) (*lists.List, error) {
+	filename, err := lists.Find(name, lists.Directory(opt.DataDir))
+	if err != nil {
+		return nil, err
+	}
+
+	return lists.Load(filename)
+}
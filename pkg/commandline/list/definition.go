@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package list
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "list",
+		Usage:           "manages the list files in the datadir",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "adds entries to a list, creating it if necessary",
+				ArgsUsage: "LIST ENTRY [ENTRY ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return addAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "remove",
+				Usage:     "removes entries from a list",
+				ArgsUsage: "LIST ENTRY [ENTRY ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return removeAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "prints all entries of a list",
+				ArgsUsage: "LIST",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return showAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "check",
+				Usage:     "prints the list entry that matches the given value",
+				ArgsUsage: "LIST VALUE",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return checkAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+		},
+	}
+}
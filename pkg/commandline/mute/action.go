@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package mute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"go.xrstf.de/rudi-lda/pkg/thread"
+)
+
+func action(_ context.Context, opt *Options, messageIDs []string) error {
+	if len(messageIDs) == 0 {
+		return errors.New("no Message-ID given")
+	}
+
+	index := thread.New(filepath.Join(opt.DataDir, "threads.json"), opt.ThreadRetention)
+
+	for _, messageID := range messageIDs {
+		if opt.Unmute {
+			root, err := index.Unmute(messageID)
+			if err != nil {
+				return fmt.Errorf("failed to unmute %q: %w", messageID, err)
+			}
+
+			fmt.Printf("unmuted thread %s\n", root)
+		} else {
+			root, err := index.Mute(messageID)
+			if err != nil {
+				return fmt.Errorf("failed to mute %q: %w", messageID, err)
+			}
+
+			fmt.Printf("muted thread %s\n", root)
+		}
+	}
+
+	return nil
+}
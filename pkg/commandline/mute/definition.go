@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package mute
+
+import (
+	"context"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir         string
+	ThreadRetention time.Duration
+	Unmute          bool
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "mute",
+		Usage:           "mutes (or unmutes) the threads of the given Message-IDs",
+		ArgsUsage:       "MESSAGE-ID [MESSAGE-ID ...]",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+			&cli.DurationFlag{
+				Name:        "thread-retention",
+				Usage:       "how long to remember delivered e-mails in the thread index",
+				Value:       90 * 24 * time.Hour,
+				Sources:     cli.EnvVars("RUDILDA_THREAD_RETENTION"),
+				Destination: &opt.ThreadRetention,
+			},
+			&cli.BoolFlag{
+				Name:        "unmute",
+				Usage:       "unmute the threads instead",
+				Destination: &opt.Unmute,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return action(ctx, opt, cmd.Args().Slice())
+		},
+	}
+}
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/bayes"
+	"go.xrstf.de/rudi-lda/pkg/kv"
+	"go.xrstf.de/rudi-lda/pkg/log"
+	maildirs "go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor/maildir"
+	"go.xrstf.de/rudi-lda/pkg/quarantine"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+)
+
+func getQuarantine(opt *Options) *quarantine.Quarantine {
+	return quarantine.New(quarantine.Directory(opt.DataDir), opt.Retention)
+}
+
+func listAction(_ context.Context, opt *Options) error {
+	q := getQuarantine(opt)
+
+	if err := q.Expire(); err != nil {
+		return fmt.Errorf("failed to expire quarantine: %w", err)
+	}
+
+	entries, err := q.List(opt.User)
+	if err != nil {
+		return fmt.Errorf("failed to list quarantine: %w", err)
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s  %s  %s  %s  %q  (%s)\n",
+			entry.ID,
+			entry.Quarantined.Format(time.DateTime),
+			entry.User,
+			entry.From,
+			entry.Subject,
+			entry.Rule,
+		)
+	}
+
+	return nil
+}
+
+func showAction(_ context.Context, opt *Options, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one ID must be given")
+	}
+
+	entry, msg, err := getQuarantine(opt).Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("id: %s\nuser: %s\nquarantined: %s\nrule: %s\nscore: %.1f\n\n",
+		entry.ID,
+		entry.User,
+		entry.Quarantined.Format(time.DateTime),
+		entry.Rule,
+		entry.Score,
+	)
+
+	_, err = os.Stdout.Write(msg.Raw())
+
+	return err
+}
+
+func releaseAction(ctx context.Context, opt *Options, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("no ID given")
+	}
+
+	if err := log.SetDirectory(opt.DataDir); err != nil {
+		return fmt.Errorf("invalid --datadir: %w", err)
+	}
+
+	rudilib.SetDataDirectory(opt.DataDir)
+	rudilib.SetKVStore(kv.New(kv.Filename(opt.DataDir)))
+
+	logger := log.New("mails.log")
+	q := getQuarantine(opt)
+
+	// metrics are not recorded for released e-mails
+	metricsData, _ := metrics.Load("")
+
+	for _, id := range ids {
+		entry, msg, err := q.Get(id)
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", id, err)
+		}
+
+		// the verdict no longer applies
+		msg.DelHeader(spam.VerdictHeader)
+		msg.SetHeader("X-Rudi-LDA-Released", time.Now().Format(time.RFC1123Z))
+
+		proc := maildir.New(maildirs.UserDirectory(opt.MailDir, entry.User), opt.FolderScript)
+
+		if _, _, err := proc.Process(ctx, logger.WithFields(msg.LogFields()).WithField("quarantine", id), msg, metricsData); err != nil {
+			return fmt.Errorf("failed to deliver %s: %w", id, err)
+		}
+
+		if opt.Train {
+			err := bayes.Update(bayes.Filename(opt.DataDir), func(db *bayes.DB) error {
+				db.Train(bayes.Tokenize(msg), false)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to train %s: %w", id, err)
+			}
+		}
+
+		if err := q.Delete(id); err != nil {
+			return fmt.Errorf("failed to remove %s from quarantine: %w", id, err)
+		}
+
+		fmt.Printf("released %s to %s\n", id, entry.User)
+	}
+
+	return nil
+}
+
+func digestAction(_ context.Context, opt *Options) error {
+	q := getQuarantine(opt)
+
+	if err := q.Expire(); err != nil {
+		return fmt.Errorf("failed to expire quarantine: %w", err)
+	}
+
+	pending, err := q.Pending()
+	if err != nil {
+		return fmt.Errorf("failed to list quarantine: %w", err)
+	}
+
+	users := make([]string, 0, len(pending))
+	for user := range pending {
+		users = append(users, user)
+	}
+
+	sort.Strings(users)
+
+	// a broken Maildir must not keep the other users from getting their digests
+	var errs []error
+
+	for _, user := range users {
+		entries := pending[user]
+
+		if err := deliverDigest(q, opt, user, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			errs = append(errs, err)
+
+			continue
+		}
+
+		fmt.Printf("delivered digest with %d e-mail(s) to %s\n", len(entries), user)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to deliver %d of %d digest(s): %w", len(errs), len(users), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func deliverDigest(q *quarantine.Quarantine, opt *Options, user string, entries []quarantine.Entry) error {
+	msg, err := quarantine.BuildDigest(opt.DigestFrom, user, entries, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to build digest for %s: %w", user, err)
+	}
+
+	md, err := maildirs.New(maildirs.UserDirectory(opt.MailDir, user))
+	if err != nil {
+		return fmt.Errorf("invalid maildir for %s: %w", user, err)
+	}
+
+	if err := md.Deliver("", msg); err != nil {
+		return fmt.Errorf("failed to deliver digest to %s: %w", user, err)
+	}
+
+	if err := q.MarkDigested(user, entries[len(entries)-1]); err != nil {
+		return fmt.Errorf("failed to record digest for %s: %w", user, err)
+	}
+
+	return nil
+}
+
+func deleteAction(_ context.Context, opt *Options, ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("no ID given")
+	}
+
+	q := getQuarantine(opt)
+
+	for _, id := range ids {
+		if err := q.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", id, err)
+		}
+
+		fmt.Printf("deleted %s\n", id)
+	}
+
+	return nil
+}
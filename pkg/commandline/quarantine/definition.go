@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package quarantine
+
+import (
+	"context"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir   string
+	Retention time.Duration
+
+	User         string
+	MailDir      string
+	FolderScript string
+	Train        bool
+	DigestFrom   string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "quarantine",
+		Usage:           "manages the spam e-mails in the quarantine",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+			&cli.DurationFlag{
+				Name:        "quarantine-retention",
+				Usage:       "how long to keep e-mails in the quarantine",
+				Value:       30 * 24 * time.Hour,
+				Sources:     cli.EnvVars("RUDILDA_QUARANTINE_RETENTION"),
+				Destination: &opt.Retention,
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "lists all quarantined e-mails, the oldest first",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "user",
+						Usage:       "only list the e-mails for this destination user",
+						Destination: &opt.User,
+					},
+				},
+				Action: func(ctx context.Context, _ *cli.Command) error {
+					return listAction(ctx, opt)
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "prints a quarantined e-mail",
+				ArgsUsage: "ID",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return showAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "release",
+				Usage:     "delivers quarantined e-mails into their user's Maildir and removes them from the quarantine",
+				ArgsUsage: "ID [ID ...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "maildir",
+						Usage:       "(required) path to the root of the user's Maildir directory",
+						Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
+						Destination: &opt.MailDir,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "folder-script",
+						Usage:       "Rudi script that will be evaluated to determine the target folder for the e-mail",
+						Sources:     cli.EnvVars("RUDILDA_FOLDER_SCRIPT"),
+						Destination: &opt.FolderScript,
+					},
+					&cli.BoolFlag{
+						Name:        "train",
+						Usage:       "train the released e-mails as ham",
+						Destination: &opt.Train,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return releaseAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:  "digest",
+				Usage: "delivers a summary of the e-mails quarantined since the last digest into each user's Maildir",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "maildir",
+						Usage:       "(required) path to the root of the user's Maildir directory",
+						Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
+						Destination: &opt.MailDir,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "from",
+						Usage:       "sender address of the digest e-mails",
+						Value:       "Rudi-LDA <rudi-lda@localhost>",
+						Destination: &opt.DigestFrom,
+					},
+				},
+				Action: func(ctx context.Context, _ *cli.Command) error {
+					return digestAction(ctx, opt)
+				},
+			},
+			{
+				Name:      "delete",
+				Usage:     "removes e-mails from the quarantine",
+				ArgsUsage: "ID [ID ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return deleteAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+		},
+	}
+}
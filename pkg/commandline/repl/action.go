@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package repl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.xrstf.de/rudi-lda/pkg/kv"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/repl"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+)
+
+func action(ctx context.Context, opt *Options, refs []string) error {
+	rudilib.SetDataDirectory(opt.DataDir)
+
+	// use the existing state, but do not modify it
+	if opt.DataDir != "" {
+		rudilib.SetKVStore(kv.New(kv.Filename(opt.DataDir)).DryRun())
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	session := repl.New(dir)
+
+	if opt.MailDir != "" {
+		md, err := maildir.New(opt.MailDir)
+		if err != nil {
+			return fmt.Errorf("invalid --maildir: %w", err)
+		}
+
+		session.WithMaildir(md)
+	}
+
+	historyFile := opt.HistoryFile
+	if historyFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			historyFile = filepath.Join(home, ".rudi-lda_history")
+		}
+	}
+
+	session.WithHistory(historyFile)
+
+	for _, ref := range refs {
+		if err := session.Open(ref); err != nil {
+			return fmt.Errorf("failed to open %s: %w", ref, err)
+		}
+	}
+
+	fmt.Println("Type :help for help.")
+
+	return session.Run(ctx, os.Stdin, os.Stdout)
+}
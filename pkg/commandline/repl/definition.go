@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package repl
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	MailDir     string
+	DataDir     string
+	HistoryFile string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "repl",
+		Usage:           "evaluates Rudi expressions interactively against sample e-mails",
+		ArgsUsage:       "[FILE|ID ...]",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "maildir",
+				Usage:       "path to a Maildir to open e-mails from by their ID",
+				Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
+				Destination: &opt.MailDir,
+			},
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "path to the data directory (used to find list files)",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+			},
+			&cli.StringFlag{
+				Name:        "history",
+				Usage:       "file to keep the history of all inputs in (default: ~/.rudi-lda_history)",
+				Destination: &opt.HistoryFile,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return action(ctx, opt, cmd.Args().Slice())
+		},
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package screener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/screener"
+)
+
+func acceptAction(_ context.Context, opt *Options, senders []string) error {
+	if len(senders) == 0 {
+		return errors.New("no senders given")
+	}
+
+	accepted, err := screener.New(opt.DataDir).Accept(senders)
+	if err != nil {
+		return fmt.Errorf("failed to accept senders: %w", err)
+	}
+
+	for _, sender := range accepted {
+		fmt.Printf("accepted %s\n", sender)
+	}
+
+	return nil
+}
+
+func rejectAction(_ context.Context, opt *Options, senders []string) error {
+	if len(senders) == 0 {
+		return errors.New("no senders given")
+	}
+
+	rejected, err := screener.New(opt.DataDir).Reject(senders)
+	if err != nil {
+		return fmt.Errorf("failed to reject senders: %w", err)
+	}
+
+	for _, sender := range rejected {
+		fmt.Printf("rejected %s\n", sender)
+	}
+
+	return nil
+}
+
+func statusAction(_ context.Context, opt *Options, addresses []string) error {
+	if len(addresses) == 0 {
+		return errors.New("no addresses given")
+	}
+
+	s := screener.New(opt.DataDir)
+
+	for _, address := range addresses {
+		status, err := s.Status(strings.ToLower(address))
+		if err != nil {
+			return fmt.Errorf("failed to screen %s: %w", address, err)
+		}
+
+		fmt.Printf("%s  %s\n", address, status)
+	}
+
+	return nil
+}
+
+func seedAction(_ context.Context, opt *Options) error {
+	md, err := maildir.New(opt.MailDir)
+	if err != nil {
+		return fmt.Errorf("invalid --maildir: %w", err)
+	}
+
+	accepted, err := screener.New(opt.DataDir).Seed(md, opt.SentFolder)
+	if err != nil {
+		return fmt.Errorf("failed to seed screener: %w", err)
+	}
+
+	for _, address := range accepted {
+		fmt.Printf("accepted %s\n", address)
+	}
+
+	fmt.Printf("%d new sender(s) accepted\n", len(accepted))
+
+	return nil
+}
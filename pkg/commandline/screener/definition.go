@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package screener
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir    string
+	MailDir    string
+	SentFolder string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "screener",
+		Usage:           "manages the senders known to the screener",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:      "accept",
+				Usage:     "accepts e-mails from the given addresses or domains",
+				ArgsUsage: "SENDER [SENDER ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return acceptAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "reject",
+				Usage:     "rejects (quarantines) e-mails from the given addresses or domains",
+				ArgsUsage: "SENDER [SENDER ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return rejectAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:      "status",
+				Usage:     "prints whether the given addresses are accepted, rejected or unknown",
+				ArgsUsage: "ADDRESS [ADDRESS ...]",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return statusAction(ctx, opt, cmd.Args().Slice())
+				},
+			},
+			{
+				Name:  "seed",
+				Usage: "accepts all recipients of the e-mails in the Sent folder",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "maildir",
+						Usage:       "(required) path to the root of the user's Maildir directory",
+						Sources:     cli.EnvVars("RUDILDA_MAILDIR"),
+						Destination: &opt.MailDir,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "sent-folder",
+						Usage:       "Maildir folder with the e-mails sent by the user",
+						Value:       "Sent",
+						Destination: &opt.SentFolder,
+					},
+				},
+				Action: func(ctx context.Context, _ *cli.Command) error {
+					return seedAction(ctx, opt)
+				},
+			},
+		},
+	}
+}
@@ -10,10 +10,24 @@ import (
 	"os"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/kv"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
 	"go.xrstf.de/rudi-lda/pkg/spam"
 )
 
 func action(ctx context.Context, opt *Options) error {
+	rudilib.SetDataDirectory(opt.DataDir)
+
+	// use the existing state, but do not modify it
+	if opt.DataDir != "" {
+		rudilib.SetKVStore(kv.New(kv.Filename(opt.DataDir)).DryRun())
+	}
+
+	thresholds, err := spam.NewThresholds(opt.MaybeSpamThreshold, opt.SpamThreshold)
+	if err != nil {
+		return fmt.Errorf("invalid spam thresholds: %w", err)
+	}
+
 	// read data from stdin
 	rawMail, err := io.ReadAll(os.Stdin)
 	if err != nil {
@@ -27,7 +41,13 @@ func action(ctx context.Context, opt *Options) error {
 	}
 
 	// run the test
-	result, err := spam.Check(ctx, opt.SpamScript, msg)
+	if opt.Trace {
+		ctx = rudilib.WithTracer(ctx, rudilib.NewTracer(func(call rudilib.Call) {
+			fmt.Println(call)
+		}))
+	}
+
+	result, err := spam.Check(ctx, opt.SpamScript, msg, thresholds)
 	if err != nil {
 		return fmt.Errorf("failed to run spam check: %w", err)
 	}
@@ -35,7 +55,11 @@ func action(ctx context.Context, opt *Options) error {
 	if result == nil {
 		fmt.Println("(no match)")
 	} else {
-		fmt.Printf("status: %s\nrule: %s\n", result.Status, result.Rule)
+		fmt.Printf("status: %s\nrule: %s\nscore: %.1f\n", result.Status, result.Rule, result.Score)
+
+		for _, hit := range result.Hits {
+			fmt.Printf("  %6.1f  %s\n", hit.Score, hit.Rule)
+		}
 	}
 
 	return nil
@@ -9,6 +9,7 @@ import (
 	"github.com/urfave/cli/v3"
 
 	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+	"go.xrstf.de/rudi-lda/pkg/spam"
 )
 
 type Options struct {
@@ -16,6 +17,11 @@ type Options struct {
 
 	SpamScript   string
 	FolderScript string
+	DataDir      string
+	Trace        bool
+
+	MaybeSpamThreshold float64
+	SpamThreshold      float64
 }
 
 func Command(commonOpt *options.CommonOptions) *cli.Command {
@@ -34,12 +40,37 @@ func Command(commonOpt *options.CommonOptions) *cli.Command {
 				Sources:     cli.EnvVars("RUDILDA_SPAM_SCRIPT"),
 				Destination: &opt.SpamScript,
 			},
+			&cli.FloatFlag{
+				Name:        "maybe-spam-threshold",
+				Usage:       "total score (see score!) at which e-mails are considered maybe-spam",
+				Value:       spam.DefaultThresholds.MaybeSpam,
+				Sources:     cli.EnvVars("RUDILDA_MAYBE_SPAM_THRESHOLD"),
+				Destination: &opt.MaybeSpamThreshold,
+			},
+			&cli.FloatFlag{
+				Name:        "spam-threshold",
+				Usage:       "total score (see score!) at which e-mails are considered spam",
+				Value:       spam.DefaultThresholds.Spam,
+				Sources:     cli.EnvVars("RUDILDA_SPAM_THRESHOLD"),
+				Destination: &opt.SpamThreshold,
+			},
 			&cli.StringFlag{
 				Name:        "folder-script",
 				Usage:       "Rudi script that will be evaluated to determine the target folder for an incoming e-mail",
 				Sources:     cli.EnvVars("RUDILDA_FOLDER_SCRIPT"),
 				Destination: &opt.FolderScript,
 			},
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "path to the data directory (used to find list files)",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+			},
+			&cli.BoolFlag{
+				Name:        "trace",
+				Usage:       "print every function call made by the script, together with its result",
+				Destination: &opt.Trace,
+			},
 		},
 		Action: func(ctx context.Context, _ *cli.Command) error {
 			return action(ctx, opt)
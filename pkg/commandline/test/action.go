@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/corpus"
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/kv"
+	maildirs "go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+	"go.xrstf.de/rudi-lda/pkg/processor/antispam"
+	"go.xrstf.de/rudi-lda/pkg/processor/maildir"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+)
+
+func action(ctx context.Context, opt *Options, paths []string) error {
+	if len(paths) == 0 {
+		return errors.New("no paths given")
+	}
+
+	rudilib.SetDataDirectory(opt.DataDir)
+
+	// use the existing state, but do not modify it
+	if opt.DataDir != "" {
+		rudilib.SetKVStore(kv.New(kv.Filename(opt.DataDir)).DryRun())
+	}
+
+	policy, err := spam.NewPolicy(opt.SpamActions, opt.MaybeSpamActions)
+	if err != nil {
+		return fmt.Errorf("invalid spam actions: %w", err)
+	}
+
+	thresholds, err := spam.NewThresholds(opt.MaybeSpamThreshold, opt.SpamThreshold)
+	if err != nil {
+		return fmt.Errorf("invalid spam thresholds: %w", err)
+	}
+
+	files, err := maildirs.CorpusFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	// nothing is ever quarantined or delivered, only the outcome is determined
+	ctx = processor.WithEffects(ctx, processor.NewDryRun(nil))
+
+	var antispamProc *antispam.Proc
+	if opt.SpamScript != "" {
+		antispamProc = antispam.New(opt.SpamScript).WithThresholds(thresholds).WithPolicy(policy, opt.SpamTag)
+	}
+
+	maildirProc := maildir.New("", opt.FolderScript).WithSpamPolicy(policy, opt.JunkFolder)
+	report := corpus.NewReport()
+
+	for _, file := range files {
+		if strings.HasSuffix(file, corpus.SidecarExtension) {
+			continue
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		msg, err := email.ParseMessage(content)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %v\n", file, err)
+			continue
+		}
+
+		expected, err := corpus.LoadExpectation(file, msg)
+		if err != nil {
+			return fmt.Errorf("invalid expectations for %s: %w", file, err)
+		}
+
+		// the scripts must not be able to peek at the expectations
+		msg.DelHeader(corpus.StatusHeader)
+		msg.DelHeader(corpus.FolderHeader)
+
+		report.Add(file, expected, evaluate(ctx, antispamProc, maildirProc, msg))
+	}
+
+	report.Print(os.Stdout, opt.Verbose)
+
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d e-mails failed", failed, len(report.Results))
+	}
+
+	return nil
+}
+
+// evaluate runs a message through the same steps as the deliver command
+// (including the antispam processor's tag action, which the folder script
+// can see), without delivering it. antispamProc is nil if there is no spam
+// script.
+func evaluate(ctx context.Context, antispamProc *antispam.Proc, maildirProc *maildir.Proc, msg *email.Message) corpus.Outcome {
+	outcome := corpus.Outcome{
+		Status: string(spam.Ham),
+	}
+
+	msg.DelHeader(spam.VerdictHeader)
+
+	if antispamProc != nil {
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+
+		consumed, updated, err := antispamProc.Process(ctx, logger, msg, &metrics.Metrics{SpamRules: map[string]int{}})
+		if err != nil {
+			outcome.Err = fmt.Errorf("spam script failed: %w", err)
+			return outcome
+		}
+
+		if verdict := spam.ParseVerdict(msg.Header.Get(spam.VerdictHeader)); verdict != nil {
+			outcome.Status = string(verdict.Status)
+			outcome.Rule = verdict.Rule
+			outcome.Score = verdict.Score
+		}
+
+		if consumed {
+			outcome.Folder = corpus.Discarded
+			return outcome
+		}
+
+		msg = updated
+	}
+
+	folder, err := maildirProc.DetermineFolder(ctx, msg, nil)
+	if err != nil {
+		outcome.Err = fmt.Errorf("folder script failed: %w", err)
+		return outcome
+	}
+
+	outcome.Folder = folder
+
+	return outcome
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/corpus"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+	"go.xrstf.de/rudi-lda/pkg/processor/antispam"
+	"go.xrstf.de/rudi-lda/pkg/processor/maildir"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+	testutil "go.xrstf.de/rudi-lda/pkg/test"
+	"go.xrstf.de/rudi-lda/pkg/test/emails"
+)
+
+func TestEvaluateSpam(t *testing.T) {
+	scriptFile, err := testutil.TempScript(`(score! 12 "foo")`)
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(scriptFile)
+
+	ctx := processor.WithEffects(context.Background(), processor.NewDryRun(nil))
+
+	antispamProc := antispam.New(scriptFile)
+	maildirProc := maildir.New("", "")
+
+	msg := emails.PatreonUpdate()
+	// verdicts from the outside must be ignored
+	msg.SetHeader(spam.VerdictHeader, "status:ham,score:0.0,rule:forged")
+
+	outcome := evaluate(ctx, antispamProc, maildirProc, msg)
+	if outcome.Err != nil {
+		t.Fatalf("Failed to evaluate e-mail: %v", outcome.Err)
+	}
+
+	if outcome.Status != string(spam.Spam) {
+		t.Errorf("Expected status %q, got %q.", spam.Spam, outcome.Status)
+	}
+
+	if outcome.Rule != "foo" {
+		t.Errorf("Expected rule %q, got %q.", "foo", outcome.Rule)
+	}
+
+	if outcome.Folder != corpus.Discarded {
+		t.Errorf("Expected folder %q, got %q.", corpus.Discarded, outcome.Folder)
+	}
+}
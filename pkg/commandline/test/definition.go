@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+	"go.xrstf.de/rudi-lda/pkg/processor/antispam"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	SpamScript   string
+	FolderScript string
+	DataDir      string
+	Verbose      bool
+
+	MaybeSpamThreshold float64
+	SpamThreshold      float64
+	SpamActions        []string
+	MaybeSpamActions   []string
+	SpamTag            string
+	JunkFolder         string
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "test",
+		Usage:           "runs the spam and folder scripts against a corpus of e-mails and compares the results with their expectations",
+		ArgsUsage:       "PATH [PATH ...]",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "spam-script",
+				Usage:       "Rudi script that will be evaluated to determine if the incoming e-mail is spam",
+				Sources:     cli.EnvVars("RUDILDA_SPAM_SCRIPT"),
+				Destination: &opt.SpamScript,
+			},
+			&cli.FloatFlag{
+				Name:        "maybe-spam-threshold",
+				Usage:       "total score (see score!) at which e-mails are considered maybe-spam",
+				Value:       spam.DefaultThresholds.MaybeSpam,
+				Sources:     cli.EnvVars("RUDILDA_MAYBE_SPAM_THRESHOLD"),
+				Destination: &opt.MaybeSpamThreshold,
+			},
+			&cli.FloatFlag{
+				Name:        "spam-threshold",
+				Usage:       "total score (see score!) at which e-mails are considered spam",
+				Value:       spam.DefaultThresholds.Spam,
+				Sources:     cli.EnvVars("RUDILDA_SPAM_THRESHOLD"),
+				Destination: &opt.SpamThreshold,
+			},
+			&cli.StringSliceFlag{
+				Name:        "spam-action",
+				Usage:       "what to do with spam: discard, junk, tag or keyword (can be given multiple times)",
+				Value:       []string{string(spam.ActionDiscard)},
+				Sources:     cli.EnvVars("RUDILDA_SPAM_ACTION"),
+				Destination: &opt.SpamActions,
+			},
+			&cli.StringSliceFlag{
+				Name:        "maybe-spam-action",
+				Usage:       "what to do with maybe-spam: spam, junk, tag or keyword (can be given multiple times)",
+				Sources:     cli.EnvVars("RUDILDA_MAYBE_SPAM_ACTION"),
+				Destination: &opt.MaybeSpamActions,
+			},
+			&cli.StringFlag{
+				Name:        "spam-tag",
+				Usage:       "subject prefix for the tag action",
+				Value:       antispam.DefaultTag,
+				Sources:     cli.EnvVars("RUDILDA_SPAM_TAG"),
+				Destination: &opt.SpamTag,
+			},
+			&cli.StringFlag{
+				Name:        "junk-folder",
+				Usage:       "Maildir folder for the junk action",
+				Value:       "Junk",
+				Sources:     cli.EnvVars("RUDILDA_JUNK_FOLDER"),
+				Destination: &opt.JunkFolder,
+			},
+			&cli.StringFlag{
+				Name:        "folder-script",
+				Usage:       "Rudi script that will be evaluated to determine the target folder for an incoming e-mail",
+				Sources:     cli.EnvVars("RUDILDA_FOLDER_SCRIPT"),
+				Destination: &opt.FolderScript,
+			},
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "path to the data directory (used to find list files)",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+			},
+			&cli.BoolFlag{
+				Name:        "verbose",
+				Aliases:     []string{"v"},
+				Usage:       "also list passed e-mails and e-mails without expectations",
+				Destination: &opt.Verbose,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return action(ctx, opt, cmd.Args().Slice())
+		},
+	}
+}
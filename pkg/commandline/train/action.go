@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package train
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.xrstf.de/rudi-lda/pkg/bayes"
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/quarantine"
+)
+
+func action(_ context.Context, opt *Options, paths []string) error {
+	if opt.Spam == opt.Ham {
+		return errors.New("exactly one of --spam or --ham must be given")
+	}
+
+	if len(paths) == 0 {
+		if !opt.Spam {
+			return errors.New("no paths given")
+		}
+
+		var err error
+
+		paths, err = defaultSpamPaths(opt.DataDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	files, err := maildir.CorpusFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	trained := 0
+
+	err = bayes.Update(bayes.Filename(opt.DataDir), func(db *bayes.DB) error {
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", file, err)
+			}
+
+			msg, err := email.ParseMessage(content)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "skipping %s: %v\n", file, err)
+				continue
+			}
+
+			if opt.Untrain {
+				db.Untrain(bayes.Tokenize(msg), opt.Spam)
+			} else {
+				db.Train(bayes.Tokenize(msg), opt.Spam)
+			}
+
+			trained++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update classifier: %w", err)
+	}
+
+	verb := "trained"
+	if opt.Untrain {
+		verb = "untrained"
+	}
+
+	kind := "ham"
+	if opt.Spam {
+		kind = "spam"
+	}
+
+	fmt.Printf("%s %d e-mails as %s\n", verb, trained, kind)
+
+	return nil
+}
+
+// defaultSpamPaths returns the directories with the quarantined e-mails and,
+// for setups that used --backup-spam before the quarantine existed, the old
+// $datadir/spam directory, as long as they exist.
+func defaultSpamPaths(datadir string) ([]string, error) {
+	candidates := []string{
+		quarantine.New(quarantine.Directory(datadir), 0).MessageDirectory(),
+		filepath.Join(datadir, "spam"),
+	}
+
+	var paths []string
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			paths = append(paths, candidate)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no paths given and neither %s nor %s exist", candidates[0], candidates[1])
+	}
+
+	return paths, nil
+}
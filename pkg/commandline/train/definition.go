@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package train
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+
+	"go.xrstf.de/rudi-lda/pkg/commandline/options"
+)
+
+type Options struct {
+	Common *options.CommonOptions
+
+	DataDir string
+	Spam    bool
+	Ham     bool
+	Untrain bool
+}
+
+func Command(commonOpt *options.CommonOptions) *cli.Command {
+	opt := &Options{
+		Common: commonOpt,
+	}
+
+	return &cli.Command{
+		Name:            "train",
+		Usage:           "trains the spam classifier with e-mails from Maildir folders, directories or files",
+		ArgsUsage:       "[PATH ...]",
+		HideHelpCommand: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "datadir",
+				Usage:       "(required) path to where metrics and other data files should be placed",
+				Sources:     cli.EnvVars("RUDILDA_DATADIR"),
+				Destination: &opt.DataDir,
+				Required:    true,
+			},
+			&cli.BoolFlag{
+				Name:        "spam",
+				Usage:       "train the e-mails as spam (without paths, the quarantined e-mails and the e-mails in $datadir/spam from before the quarantine existed are used)",
+				Destination: &opt.Spam,
+			},
+			&cli.BoolFlag{
+				Name:        "ham",
+				Usage:       "train the e-mails as ham",
+				Destination: &opt.Ham,
+			},
+			&cli.BoolFlag{
+				Name:        "untrain",
+				Usage:       "remove previously trained e-mails from the classifier instead",
+				Destination: &opt.Untrain,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return action(ctx, opt, cmd.Args().Slice())
+		},
+	}
+}
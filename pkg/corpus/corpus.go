@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package corpus
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+const (
+	// SidecarExtension is appended to the filename of an e-mail to find its
+	// expectations file.
+	SidecarExtension = ".expect"
+
+	// StatusHeader and FolderHeader contain the expected results inside the
+	// e-mail itself.
+	StatusHeader = "X-Expect-Spam"
+	FolderHeader = "X-Expect-Folder"
+
+	// Inbox is how the inbox is named in expectations and reports.
+	Inbox = "INBOX"
+	// Discarded is the folder of e-mails that would have been discarded.
+	Discarded = "(discarded)"
+)
+
+// Expectation is the expected outcome for a single e-mail. Empty fields are
+// not checked.
+type Expectation struct {
+	Status string
+	Folder string
+}
+
+func (e Expectation) Empty() bool {
+	return e.Status == "" && e.Folder == ""
+}
+
+// LoadExpectation reads the expectations for an e-mail, either from its
+// sidecar file (e.g. "mail.eml.expect") or from its X-Expect-* headers.
+// The sidecar file uses the same format as the headers, but without the
+// "X-Expect-" prefix, e.g. "Spam: ham" and "Folder: Lists.Go".
+func LoadExpectation(file string, msg *email.Message) (Expectation, error) {
+	exp := Expectation{
+		Status: strings.TrimSpace(msg.Header.Get(StatusHeader)),
+		Folder: strings.TrimSpace(msg.Header.Get(FolderHeader)),
+	}
+
+	content, err := os.ReadFile(file + SidecarExtension)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return exp, nil
+		}
+
+		return exp, fmt.Errorf("failed to read expectations: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return exp, fmt.Errorf("invalid line %q in expectations", line)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "spam":
+			exp.Status = strings.TrimSpace(value)
+		case "folder":
+			exp.Folder = strings.TrimSpace(value)
+		default:
+			return exp, fmt.Errorf("unknown expectation %q", key)
+		}
+	}
+
+	return exp, nil
+}
+
+// Outcome is what the scripts decided for an e-mail.
+type Outcome struct {
+	Status string
+	Rule   string
+	Score  float64
+	Folder string
+	Err    error
+}
+
+// Result is the comparison of the expected and actual outcome.
+type Result struct {
+	File     string
+	Expected Expectation
+	Actual   Outcome
+	// Diff contains one line per mismatch.
+	Diff []string
+}
+
+func (r Result) Passed() bool {
+	return len(r.Diff) == 0
+}
+
+// Report collects the results for a corpus.
+type Report struct {
+	Results []Result
+	// Matrix counts the actual spam statuses per expected status.
+	Matrix map[string]map[string]int
+}
+
+func NewReport() *Report {
+	return &Report{
+		Matrix: map[string]map[string]int{},
+	}
+}
+
+// Add compares the outcome for a file with the expectations.
+func (r *Report) Add(file string, expected Expectation, actual Outcome) Result {
+	result := Result{
+		File:     file,
+		Expected: expected,
+		Actual:   actual,
+	}
+
+	if actual.Err != nil {
+		result.Diff = append(result.Diff, fmt.Sprintf("error: %v", actual.Err))
+	}
+
+	if expected.Status != "" {
+		if r.Matrix[expected.Status] == nil {
+			r.Matrix[expected.Status] = map[string]int{}
+		}
+
+		r.Matrix[expected.Status][actual.Status]++
+
+		if expected.Status != actual.Status {
+			result.Diff = append(result.Diff,
+				fmt.Sprintf("- spam: %s", expected.Status),
+				fmt.Sprintf("+ spam: %s (rule %q, score %.1f)", actual.Status, actual.Rule, actual.Score),
+			)
+		}
+	}
+
+	if expected.Folder != "" && !sameFolder(expected.Folder, actual.Folder) {
+		result.Diff = append(result.Diff,
+			fmt.Sprintf("- folder: %s", displayFolder(expected.Folder)),
+			fmt.Sprintf("+ folder: %s", displayFolder(actual.Folder)),
+		)
+	}
+
+	r.Results = append(r.Results, result)
+
+	return result
+}
+
+// Failed returns the number of failed e-mails.
+func (r *Report) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if !result.Passed() {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Print writes the report. Passed and skipped e-mails are only listed if
+// verbose is true.
+func (r *Report) Print(w io.Writer, verbose bool) {
+	skipped := 0
+
+	for _, result := range r.Results {
+		switch {
+		case !result.Passed():
+			fmt.Fprintf(w, "FAIL  %s\n", result.File)
+			for _, line := range result.Diff {
+				fmt.Fprintf(w, "      %s\n", line)
+			}
+
+		case result.Expected.Empty():
+			skipped++
+			if verbose {
+				fmt.Fprintf(w, "SKIP  %s (spam: %s, folder: %s)\n", result.File, result.Actual.Status, displayFolder(result.Actual.Folder))
+			}
+
+		case verbose:
+			fmt.Fprintf(w, "PASS  %s\n", result.File)
+		}
+	}
+
+	if len(r.Matrix) > 0 {
+		fmt.Fprintln(w)
+		r.printMatrix(w)
+	}
+
+	failed := r.Failed()
+	fmt.Fprintf(w, "\n%d e-mails: %d passed, %d failed, %d without expectations\n", len(r.Results), len(r.Results)-failed-skipped, failed, skipped)
+}
+
+func (r *Report) printMatrix(w io.Writer) {
+	statuses := map[string]struct{}{}
+	for expected, actuals := range r.Matrix {
+		statuses[expected] = struct{}{}
+		for actual := range actuals {
+			statuses[actual] = struct{}{}
+		}
+	}
+
+	columns := make([]string, 0, len(statuses))
+	for status := range statuses {
+		columns = append(columns, status)
+	}
+
+	sort.Strings(columns)
+
+	fmt.Fprintf(w, "%-20s", "expected \\ actual")
+	for _, column := range columns {
+		fmt.Fprintf(w, "%12s", column)
+	}
+	fmt.Fprintln(w)
+
+	for _, expected := range columns {
+		actuals, ok := r.Matrix[expected]
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(w, "%-20s", expected)
+		for _, actual := range columns {
+			fmt.Fprintf(w, "%12d", actuals[actual])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func sameFolder(expected string, actual string) bool {
+	return displayFolder(expected) == displayFolder(actual)
+}
+
+func displayFolder(folder string) string {
+	if folder == "" || strings.EqualFold(folder, Inbox) {
+		return Inbox
+	}
+
+	return folder
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package corpus
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+func TestLoadExpectation(t *testing.T) {
+	dir := t.TempDir()
+
+	msg, err := email.ParseMessage([]byte("X-Expect-Spam: maybe-spam\r\nX-Expect-Folder: Lists.Go\r\nSubject: test\r\n\r\nBody\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	testcases := []struct {
+		name     string
+		sidecar  string
+		expected Expectation
+		invalid  bool
+	}{
+		{
+			name:     "headers only",
+			expected: Expectation{Status: "maybe-spam", Folder: "Lists.Go"},
+		},
+		{
+			name:     "sidecar overrides headers",
+			sidecar:  "# comment\nSpam: spam\n",
+			expected: Expectation{Status: "spam", Folder: "Lists.Go"},
+		},
+		{
+			name:     "sidecar with folder",
+			sidecar:  "folder: INBOX\n",
+			expected: Expectation{Status: "maybe-spam", Folder: "INBOX"},
+		},
+		{
+			name:    "invalid sidecar",
+			sidecar: "Rule: foo\n",
+			invalid: true,
+		},
+	}
+
+	for i, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.Repeat("x", i+1)+".eml")
+
+			if testcase.sidecar != "" {
+				if err := os.WriteFile(file+SidecarExtension, []byte(testcase.sidecar), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			exp, err := LoadExpectation(file, msg)
+			if testcase.invalid {
+				if err == nil {
+					t.Fatal("Expected an error, got nil.")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to load expectation: %v", err)
+			}
+
+			if exp != testcase.expected {
+				t.Fatalf("Expected %+v, got %+v.", testcase.expected, exp)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := NewReport()
+
+	testcases := []struct {
+		expected Expectation
+		actual   Outcome
+		passed   bool
+	}{
+		{
+			expected: Expectation{Status: "spam"},
+			actual:   Outcome{Status: "spam"},
+			passed:   true,
+		},
+		{
+			expected: Expectation{Status: "spam"},
+			actual:   Outcome{Status: "ham"},
+			passed:   false,
+		},
+		{
+			expected: Expectation{Status: "ham", Folder: "INBOX"},
+			actual:   Outcome{Status: "ham", Folder: ""},
+			passed:   true,
+		},
+		{
+			expected: Expectation{Folder: "Lists.Go"},
+			actual:   Outcome{Status: "ham", Folder: "Lists.Rust"},
+			passed:   false,
+		},
+		{
+			expected: Expectation{},
+			actual:   Outcome{Status: "ham"},
+			passed:   true,
+		},
+		{
+			expected: Expectation{},
+			actual:   Outcome{Err: errors.New("script failed")},
+			passed:   false,
+		},
+	}
+
+	for i, testcase := range testcases {
+		result := report.Add(strings.Repeat("x", i+1), testcase.expected, testcase.actual)
+		if result.Passed() != testcase.passed {
+			t.Errorf("Expected case %d to pass=%v, got diff %v.", i, testcase.passed, result.Diff)
+		}
+	}
+
+	if failed := report.Failed(); failed != 3 {
+		t.Errorf("Expected 3 failures, got %d.", failed)
+	}
+
+	if report.Matrix["spam"]["spam"] != 1 || report.Matrix["spam"]["ham"] != 1 || report.Matrix["ham"]["ham"] != 1 {
+		t.Errorf("Unexpected confusion matrix %v.", report.Matrix)
+	}
+
+	var buf bytes.Buffer
+	report.Print(&buf, false)
+
+	output := buf.String()
+	for _, expected := range []string{"FAIL  xx\n", "- folder: Lists.Go", "+ folder: Lists.Rust", "6 e-mails: 2 passed, 3 failed, 1 without expectations"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected report to contain %q:\n%s", expected, output)
+		}
+	}
+}
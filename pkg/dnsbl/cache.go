@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package dnsbl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+type cacheEntry struct {
+	// Result is the listing code, empty if the name was not listed.
+	Result  string    `json:"result"`
+	Expires time.Time `json:"expires"`
+}
+
+// cache keeps query results in memory and, if a filename is given, in a
+// file that is shared between deliveries.
+type cache struct {
+	filename string
+	ttl      time.Duration
+
+	lock    sync.Mutex
+	loaded  bool
+	entries map[string]cacheEntry
+}
+
+func newCache(filename string, ttl time.Duration) *cache {
+	return &cache{
+		filename: filename,
+		ttl:      ttl,
+		entries:  map[string]cacheEntry{},
+	}
+}
+
+func (c *cache) get(name string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.loaded && c.filename != "" {
+		// a broken cache file is not worth failing over
+		if entries, err := c.load(); err == nil {
+			c.entries = entries
+		}
+
+		c.loaded = true
+	}
+
+	entry, ok := c.entries[name]
+	if !ok || time.Now().After(entry.Expires) {
+		return "", false
+	}
+
+	return entry.Result, true
+}
+
+func (c *cache) set(name string, result string) error {
+	entry := cacheEntry{
+		Result:  result,
+		Expires: time.Now().Add(c.ttl),
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries[name] = entry
+
+	if c.filename == "" {
+		return nil
+	}
+
+	unlock, err := fs.LockFile(c.filename + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := c.load()
+	if err != nil {
+		// start over instead of failing on every query
+		entries = map[string]cacheEntry{}
+	}
+
+	entries[name] = entry
+
+	now := time.Now()
+	for key, e := range entries {
+		if now.After(e.Expires) {
+			delete(entries, key)
+		}
+	}
+
+	encoded, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	return fs.WriteFileAtomic(c.filename, encoded)
+}
+
+func (c *cache) load() (map[string]cacheEntry, error) {
+	entries := map[string]cacheEntry{}
+
+	content, err := os.ReadFile(c.filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entries, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("invalid cache file: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when a session has used up all its queries.
+var ErrBudgetExceeded = errors.New("DNS query budget exceeded")
+
+const (
+	DefaultTimeout = 2 * time.Second
+	DefaultBudget  = 20
+)
+
+// Checker queries DNS-based blocklists. Results are cached in a file for the
+// given TTL, so that other deliveries can reuse them.
+type Checker struct {
+	resolver Resolver
+	cache    *cache
+	timeout  time.Duration
+	budget   int
+}
+
+// NewChecker returns a new checker. If cacheFile is empty, results are only
+// cached in memory.
+func NewChecker(resolver Resolver, cacheFile string, ttl time.Duration) *Checker {
+	return &Checker{
+		resolver: resolver,
+		cache:    newCache(cacheFile, ttl),
+		timeout:  DefaultTimeout,
+		budget:   DefaultBudget,
+	}
+}
+
+// WithTimeout sets the timeout for each individual query.
+func (c *Checker) WithTimeout(timeout time.Duration) *Checker {
+	c.timeout = timeout
+	return c
+}
+
+// WithBudget sets the maximum number of queries per session (i.e. message).
+// Cached results do not count against the budget.
+func (c *Checker) WithBudget(budget int) *Checker {
+	c.budget = budget
+	return c
+}
+
+// Session is used to check a single message.
+type Session struct {
+	checker   *Checker
+	lock      sync.Mutex
+	remaining int
+}
+
+func (c *Checker) NewSession() *Session {
+	return &Session{
+		checker:   c,
+		remaining: c.budget,
+	}
+}
+
+// CheckIP queries the zone for the given IPv4 or IPv6 address. It returns the
+// listing code (e.g. "127.0.0.2") or an empty string if the IP is not listed.
+func (s *Session) CheckIP(ctx context.Context, ip string, zone string) (string, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return "", fmt.Errorf("invalid IP %q: %w", ip, err)
+	}
+
+	return s.query(ctx, reverseIP(addr.Unmap())+"."+normalizeZone(zone))
+}
+
+// CheckDomain queries the zone for the given domain. It returns the listing
+// code or an empty string if the domain is not listed.
+func (s *Session) CheckDomain(ctx context.Context, domain string, zone string) (string, error) {
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return "", errors.New("no domain given")
+	}
+
+	return s.query(ctx, domain+"."+normalizeZone(zone))
+}
+
+func (s *Session) query(ctx context.Context, name string) (string, error) {
+	if result, ok := s.checker.cache.get(name); ok {
+		return result, nil
+	}
+
+	s.lock.Lock()
+	if s.remaining <= 0 {
+		s.lock.Unlock()
+		return "", ErrBudgetExceeded
+	}
+	s.remaining--
+	s.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.checker.timeout)
+	defer cancel()
+
+	// the trailing dot prevents the resolver from trying search domains
+	addrs, err := s.checker.resolver.LookupHost(ctx, name+".")
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("failed to query %s: %w", name, err)
+	}
+
+	result := listingCode(addrs)
+
+	if err := s.checker.cache.set(name, result); err != nil {
+		return "", fmt.Errorf("failed to cache result: %w", err)
+	}
+
+	return result, nil
+}
+
+// listingCode returns the first address in 127.0.0.0/8. Addresses in
+// 127.255.255.0/24 are error codes (e.g. for rate limiting) and do not
+// count as a listing.
+func listingCode(addrs []string) string {
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil || !addr.Is4() {
+			continue
+		}
+
+		octets := addr.As4()
+		if octets[0] != 127 || (octets[1] == 255 && octets[2] == 255) {
+			continue
+		}
+
+		return addr.String()
+	}
+
+	return ""
+}
+
+func normalizeZone(zone string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(zone)), ".")
+}
+
+// reverseIP turns 192.0.2.1 into 1.2.0.192 and IPv6 addresses into their
+// reversed nibble format.
+func reverseIP(addr netip.Addr) string {
+	if addr.Is4() {
+		octets := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d", octets[3], octets[2], octets[1], octets[0])
+	}
+
+	bytes := addr.As16()
+	nibbles := make([]string, 0, 32)
+
+	for i := len(bytes) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x", bytes[i]&0x0f), fmt.Sprintf("%x", bytes[i]>>4))
+	}
+
+	return strings.Join(nibbles, ".")
+}
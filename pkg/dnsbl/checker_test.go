@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/test"
+)
+
+func TestCheck(t *testing.T) {
+	server := test.NewDNSServer(t, map[string][]string{
+		"2.0.0.127.zen.example.org":        {"127.0.0.2", "127.0.0.4"},
+		"1.2.0.192.zen.example.org":        {"127.255.255.254"},
+		"spam.example.com.dbl.example.org": {"127.0.1.2"},
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.zen.example.org": {"127.0.0.3"},
+	})
+
+	checker := NewChecker(NewResolver(server.Addr), "", time.Hour)
+	session := checker.NewSession()
+	ctx := context.Background()
+
+	testcases := []struct {
+		ip       string
+		domain   string
+		zone     string
+		expected string
+	}{
+		{ip: "127.0.0.2", zone: "zen.example.org", expected: "127.0.0.2"},
+		{ip: "127.0.0.2", zone: "ZEN.example.org.", expected: "127.0.0.2"},
+		{ip: "192.0.2.99", zone: "zen.example.org"},
+		// error codes are no listings
+		{ip: "192.0.2.1", zone: "zen.example.org"},
+		{ip: "2001:db8::1", zone: "zen.example.org", expected: "127.0.0.3"},
+		{domain: "Spam.Example.com", zone: "dbl.example.org", expected: "127.0.1.2"},
+		{domain: "example.com", zone: "dbl.example.org"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.ip+tc.domain, func(t *testing.T) {
+			var (
+				result string
+				err    error
+			)
+
+			if tc.ip != "" {
+				result, err = session.CheckIP(ctx, tc.ip, tc.zone)
+			} else {
+				result, err = session.CheckDomain(ctx, tc.domain, tc.zone)
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to check: %v", err)
+			}
+
+			if result != tc.expected {
+				t.Fatalf("Expected %q, got %q.", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestBudgetAndCache(t *testing.T) {
+	server := test.NewDNSServer(t, map[string][]string{
+		"2.0.0.127.zen.example.org": {"127.0.0.2"},
+	})
+
+	cacheFile := filepath.Join(t.TempDir(), "dnsbl.json")
+	ctx := context.Background()
+
+	checker := NewChecker(NewResolver(server.Addr), cacheFile, time.Hour).WithBudget(1)
+	session := checker.NewSession()
+
+	if result, err := session.CheckIP(ctx, "127.0.0.2", "zen.example.org"); err != nil || result != "127.0.0.2" {
+		t.Fatalf("Expected listing, got %q (%v).", result, err)
+	}
+
+	// cached results do not count against the budget
+	if result, err := session.CheckIP(ctx, "127.0.0.2", "zen.example.org"); err != nil || result != "127.0.0.2" {
+		t.Fatalf("Expected cached listing, got %q (%v).", result, err)
+	}
+
+	if _, err := session.CheckIP(ctx, "127.0.0.3", "zen.example.org"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected budget to be exceeded, got %v.", err)
+	}
+
+	// a new checker must use the cache file
+	other := NewChecker(NewResolver(server.Addr), cacheFile, time.Hour).NewSession()
+	if result, err := other.CheckIP(ctx, "127.0.0.2", "zen.example.org"); err != nil || result != "127.0.0.2" {
+		t.Fatalf("Expected cached listing, got %q (%v).", result, err)
+	}
+
+	if queries := server.Queries(); len(queries) != 1 {
+		t.Fatalf("Expected exactly 1 query, got %v.", queries)
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrNotFound is returned by resolvers if a name does not exist, i.e. it is
+// not listed.
+var ErrNotFound = errors.New("no such host")
+
+// Resolver looks up the A records of a name.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+type netResolver struct {
+	resolver *net.Resolver
+}
+
+// NewResolver returns a resolver that uses the system's DNS configuration or,
+// if server is not empty, the given DNS server ("host:port").
+func NewResolver(server string) Resolver {
+	resolver := &net.Resolver{}
+
+	if server != "" {
+		dialer := &net.Dialer{}
+
+		resolver.PreferGo = true
+		resolver.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server)
+		}
+	}
+
+	return &netResolver{
+		resolver: resolver,
+	}
+}
+
+func (r *netResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// DSN is a parsed delivery status notification (bounce).
+type DSN struct {
+	// Standard is true if the DSN was parsed from a RFC 3464 report, false
+	// if it was guessed from a non-standard bounce message.
+	Standard          bool           `json:"standard"`
+	ReportingMTA      string         `json:"reportingMta"`
+	OriginalMessageID string         `json:"originalMessageId"`
+	Recipients        []DSNRecipient `json:"recipients"`
+}
+
+// DSNRecipient is the delivery status for a single recipient.
+type DSNRecipient struct {
+	Recipient string `json:"recipient"`
+	// Action is one of failed, delayed, delivered, relayed or expanded.
+	Action string `json:"action"`
+	// Status is the enhanced status code, e.g. "5.1.1".
+	Status     string `json:"status"`
+	Diagnostic string `json:"diagnostic"`
+}
+
+// Failed returns all recipients for which the delivery failed permanently.
+func (d *DSN) Failed() []DSNRecipient {
+	var failed []DSNRecipient
+
+	for _, r := range d.Recipients {
+		if r.Action == "failed" || (r.Action == "" && strings.HasPrefix(r.Status, "5")) {
+			failed = append(failed, r)
+		}
+	}
+
+	return failed
+}
+
+var (
+	bounceSubjectRegex = regexp.MustCompile(`(?i)(undeliver|undelivered mail|delivery (status notification|failure|has failed)|mail delivery (failed|failure|subsystem)|returned mail|failure notice|could not be delivered|non[- ]?delivery)`)
+	bounceSenderRegex  = regexp.MustCompile(`(?i)^(mailer-daemon|postmaster)@`)
+	enhancedCodeRegex  = regexp.MustCompile(`\b([245]\.\d{1,3}\.\d{1,3})\b`)
+	smtpCodeRegex      = regexp.MustCompile(`(?m)\b([45]\d\d)[ -].*$`)
+	addressRegex       = regexp.MustCompile(`<?([a-zA-Z0-9._%+=-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,})>?`)
+	messageIDRegex     = regexp.MustCompile(`(?im)^Message-ID:\s*(<[^>]+>)`)
+)
+
+// ParseDSN parses RFC 3464 delivery status notifications and tries to make
+// sense of common non-standard bounce messages. If the message is not a
+// bounce, nil is returned.
+func (m *Message) ParseDSN() (*DSN, error) {
+	parts, err := m.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, part := range parts {
+		if part.ContentType == "message/delivery-status" || part.ContentType == "message/global-delivery-status" {
+			dsn := parseDeliveryStatus(part.Body)
+			dsn.OriginalMessageID = findOriginalMessageID(parts)
+
+			return dsn, nil
+		}
+	}
+
+	if !m.looksLikeBounce() {
+		return nil, nil
+	}
+
+	return guessDSN(parts), nil
+}
+
+func (m *Message) looksLikeBounce() bool {
+	if from := m.GetFrom(); from != nil && bounceSenderRegex.MatchString(from.Address) {
+		return true
+	}
+
+	// bounces are sent with an empty envelope sender
+	if m.Header.Get("Return-Path") == "<>" {
+		return true
+	}
+
+	return bounceSubjectRegex.MatchString(m.GetSubject())
+}
+
+// parseDeliveryStatus parses the message/delivery-status part, which consists
+// of one block of per-message fields and one block per recipient.
+func parseDeliveryStatus(body []byte) *DSN {
+	dsn := &DSN{
+		Standard: true,
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(body)))
+
+	perMessage, _ := reader.ReadMIMEHeader()
+	dsn.ReportingMTA = stripAddressType(perMessage.Get("Reporting-Mta"))
+
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			recipient := fields.Get("Final-Recipient")
+			if recipient == "" {
+				recipient = fields.Get("Original-Recipient")
+			}
+
+			dsn.Recipients = append(dsn.Recipients, DSNRecipient{
+				Recipient:  stripAddressType(recipient),
+				Action:     strings.ToLower(strings.TrimSpace(fields.Get("Action"))),
+				Status:     strings.TrimSpace(fields.Get("Status")),
+				Diagnostic: stripAddressType(fields.Get("Diagnostic-Code")),
+			})
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	return dsn
+}
+
+// stripAddressType removes the "rfc822;" or "smtp;" prefix from DSN fields.
+func stripAddressType(value string) string {
+	if _, after, found := strings.Cut(value, ";"); found {
+		value = after
+	}
+
+	return strings.TrimSpace(value)
+}
+
+func findOriginalMessageID(parts []Part) string {
+	for _, part := range parts {
+		switch part.ContentType {
+		case "message/rfc822", "text/rfc822-headers", "message/global", "message/global-headers":
+			if original, err := mail.ReadMessage(bytes.NewReader(part.Body)); err == nil {
+				if ids := parseMessageIDs(original.Header.Get("Message-Id")); len(ids) > 0 {
+					return ids[0]
+				}
+			}
+		}
+	}
+
+	// some MTAs only quote the original headers in the text
+	for _, part := range parts {
+		if match := messageIDRegex.FindSubmatch(part.Body); match != nil {
+			return NormalizeMessageID(string(match[1]))
+		}
+	}
+
+	return ""
+}
+
+// guessDSN extracts recipients and status codes from the human readable
+// text of non-standard bounces (e.g. qmail or Exim).
+func guessDSN(parts []Part) *DSN {
+	dsn := &DSN{
+		OriginalMessageID: findOriginalMessageID(parts),
+	}
+
+	var text string
+	for _, part := range parts {
+		if part.ContentType == "text/plain" {
+			text = string(part.Body)
+			break
+		}
+	}
+
+	// only look at the explanation, not at the quoted original message
+	for _, marker := range []string{"--- Below this line is a copy of the message", "------ This is a copy of the message", "Original message follows", "----- Original message -----"} {
+		if idx := strings.Index(text, marker); idx >= 0 {
+			text = text[:idx]
+		}
+	}
+
+	recipient := DSNRecipient{
+		Action: "failed",
+	}
+
+	if match := addressRegex.FindStringSubmatch(text); match != nil {
+		recipient.Recipient = match[1]
+	}
+
+	if match := enhancedCodeRegex.FindStringSubmatch(text); match != nil {
+		recipient.Status = match[1]
+	}
+
+	if match := smtpCodeRegex.FindString(text); match != "" {
+		recipient.Diagnostic = strings.TrimSpace(match)
+
+		if recipient.Status == "" {
+			recipient.Status = string(match[0]) + ".0.0"
+		}
+	}
+
+	// 4xx errors are only temporary
+	if strings.HasPrefix(recipient.Status, "4") {
+		recipient.Action = "delayed"
+	}
+
+	if recipient.Recipient != "" {
+		dsn.Recipients = append(dsn.Recipients, recipient)
+	}
+
+	return dsn
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDSN(t *testing.T) {
+	testcases := []struct {
+		filename string
+		expected *DSN
+	}{
+		{
+			filename: "dsn-standard.eml",
+			expected: &DSN{
+				Standard:          true,
+				ReportingMTA:      "mx.example.com",
+				OriginalMessageID: "original-123@example.org",
+				Recipients: []DSNRecipient{{
+					Recipient:  "nobody@example.com",
+					Action:     "failed",
+					Status:     "5.1.1",
+					Diagnostic: "550 5.1.1 <nobody@example.com>: Recipient address rejected: User unknown in local recipient table",
+				}},
+			},
+		},
+		{
+			filename: "dsn-qmail.eml",
+			expected: &DSN{
+				OriginalMessageID: "original-456@example.org",
+				Recipients: []DSNRecipient{{
+					Recipient: "gone@example.net",
+					Action:    "failed",
+					Status:    "5.1.1",
+				}},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.filename, func(t *testing.T) {
+			content, err := os.ReadFile("testdata/" + testcase.filename)
+			if err != nil {
+				t.Fatalf("Failed to read testdata: %v", err)
+			}
+
+			msg, err := ParseMessage(content)
+			if err != nil {
+				t.Fatalf("Failed to parse mail body: %v", err)
+			}
+
+			dsn, err := msg.ParseDSN()
+			if err != nil {
+				t.Fatalf("Failed to parse DSN: %v", err)
+			}
+
+			if diff := cmp.Diff(testcase.expected, dsn); diff != "" {
+				t.Fatalf("Unexpected DSN:\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("regular e-mail", func(t *testing.T) {
+		msg, err := ParseMessage([]byte(testMessage))
+		if err != nil {
+			t.Fatalf("Failed to parse mail body: %v", err)
+		}
+
+		dsn, err := msg.ParseDSN()
+		if err != nil {
+			t.Fatalf("Failed to parse DSN: %v", err)
+		}
+
+		if dsn != nil {
+			t.Fatalf("Expected no DSN, got %+v", dsn)
+		}
+	})
+}
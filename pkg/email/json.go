@@ -19,6 +19,9 @@ type JSONMessage struct {
 	Date        time.Time      `json:"date"`
 	Body        string         `json:"body"`
 	Headers     mail.Header    `json:"headers"`
+	RelayIP     string         `json:"relayIP"`
+	DSN         *DSN           `json:"dsn"`
+	URLs        []URL          `json:"urls"`
 }
 
 func addressToJSON(addr *mail.Address) map[string]any {
@@ -44,6 +47,7 @@ func (m *Message) ToJSON() (any, error) {
 	rm.DeliveredTo = m.GetDeliveredTo()
 	rm.Body = m.Body
 	rm.Headers = m.Header
+	rm.RelayIP = m.GetRelayIP()
 
 	date, err := m.GetDate()
 	if err != nil {
@@ -52,6 +56,15 @@ func (m *Message) ToJSON() (any, error) {
 
 	rm.Date = date
 
+	// bounces are best-effort, a broken MIME structure should not make
+	// the message unprocessable
+	rm.DSN, _ = m.ParseDSN()
+
+	rm.URLs, _ = m.URLs()
+	if rm.URLs == nil {
+		rm.URLs = []URL{}
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(rm); err != nil {
 		return nil, err
@@ -22,6 +22,10 @@ type Message struct {
 	Header mail.Header
 	Body   string
 	raw    []byte
+
+	// original state after parsing, used to detect modifications
+	original     mail.Header
+	originalBody string
 }
 
 func ParseMessage(rawMessage []byte) (*Message, error) {
@@ -33,9 +37,11 @@ func ParseMessage(rawMessage []byte) (*Message, error) {
 	body, _ := io.ReadAll(msg.Body)
 
 	return &Message{
-		Header: msg.Header,
-		Body:   string(body),
-		raw:    rawMessage,
+		Header:       msg.Header,
+		Body:         string(body),
+		raw:          rawMessage,
+		original:     cloneHeader(msg.Header),
+		originalBody: string(body),
 	}, nil
 }
 
@@ -57,12 +63,24 @@ func (m *Message) LogFields() logrus.Fields {
 	return fields
 }
 
+// Raw returns the message in its wire format. Unless the headers or the body
+// have been modified, this is exactly the data the message was parsed from.
+// Otherwise, it is rendered from the current headers and body, so that all
+// headers added or changed by the processors are persisted.
 func (m *Message) Raw() []byte {
-	return m.raw
+	if !m.modified() {
+		return m.raw
+	}
+
+	return m.render()
 }
 
 func (m *Message) GetDate() (time.Time, error) {
-	date := m.Header.Get("Date")
+	return ParseDate(m.Header.Get("Date"))
+}
+
+// ParseDate leniently parses the value of a Date header.
+func ParseDate(date string) (time.Time, error) {
 	formats := []string{
 		time.RFC822, time.RFC822Z,
 		// time.RFC1123 but with _2 instead of 02
@@ -102,6 +120,23 @@ func (m *Message) GetDeliveredTo() string {
 	return m.Header.Get("Delivered-To")
 }
 
+func (m *Message) GetMessageID() string {
+	ids := parseMessageIDs(m.Header.Get("Message-Id"))
+	if len(ids) == 0 {
+		return ""
+	}
+
+	return ids[0]
+}
+
+func (m *Message) GetInReplyTo() []string {
+	return parseMessageIDs(m.Header.Get("In-Reply-To"))
+}
+
+func (m *Message) GetReferences() []string {
+	return parseMessageIDs(strings.Join(m.Header["References"], " "))
+}
+
 func (m *Message) getAddress(header string) *mail.Address {
 	list, err := m.Header.AddressList(header)
 	if err != nil || len(list) == 0 {
@@ -180,6 +215,43 @@ func (m *Message) GetMultipartBody(contentType string) (string, error) {
 	return "", nil
 }
 
+// parseMessageIDs returns all "<...>" message IDs (without the angle brackets)
+// from a header value. Malformed values without brackets are returned as-is.
+func parseMessageIDs(value string) []string {
+	var ids []string
+
+	for {
+		start := strings.Index(value, "<")
+		if start < 0 {
+			break
+		}
+
+		end := strings.Index(value[start:], ">")
+		if end < 0 {
+			break
+		}
+
+		if id := strings.TrimSpace(value[start+1 : start+end]); id != "" {
+			ids = append(ids, id)
+		}
+
+		value = value[start+end+1:]
+	}
+
+	if len(ids) == 0 {
+		if id := NormalizeMessageID(value); id != "" && !strings.ContainsAny(id, " \t") {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// NormalizeMessageID strips whitespace and angle brackets from a Message-ID.
+func NormalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
+
 func decodeQuotedPrintable(s string) string {
 	dec := new(mime.WordDecoder)
 	b, _ := dec.DecodeHeader(s)
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"unicode"
+)
+
+// SetSubject replaces the subject, encoding it if it contains non-ASCII
+// characters.
+func (m *Message) SetSubject(subject string) {
+	m.SetHeader("Subject", subject)
+}
+
+// SetHeader replaces all values of a header, encoding the value if it
+// contains non-ASCII characters.
+func (m *Message) SetHeader(name string, value string) {
+	m.Header[textproto.CanonicalMIMEHeaderKey(name)] = []string{encodeHeaderValue(value)}
+}
+
+// AddHeader adds another value to a header, encoding it if it contains
+// non-ASCII characters.
+func (m *Message) AddHeader(name string, value string) {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	m.Header[name] = append(m.Header[name], encodeHeaderValue(value))
+}
+
+// DelHeader removes all values of a header.
+func (m *Message) DelHeader(name string) {
+	delete(m.Header, textproto.CanonicalMIMEHeaderKey(name))
+}
+
+func encodeHeaderValue(value string) string {
+	for _, r := range value {
+		if r > unicode.MaxASCII {
+			return mime.QEncoding.Encode("utf-8", value)
+		}
+	}
+
+	return value
+}
+
+// StripParts removes all leaf parts for which the match function returns
+// true and replaces them with a short text/plain note. It returns the number
+// of removed parts. Non-multipart messages are never modified.
+func (m *Message) StripParts(match func(p *Part) bool) (int, error) {
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return 0, nil
+	}
+
+	body, stripped, err := stripMultipart([]byte(m.Body), params["boundary"], match, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if stripped > 0 {
+		m.Body = string(body)
+	}
+
+	return stripped, nil
+}
+
+func stripMultipart(body []byte, boundary string, match func(p *Part) bool, depth int) ([]byte, int, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	var buf bytes.Buffer
+
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, 0, fmt.Errorf("invalid boundary: %w", err)
+	}
+
+	total := 0
+
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed going through the MIME parts: %w", err)
+		}
+
+		partBody, err := io.ReadAll(part)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read MIME body: %w", err)
+		}
+
+		header := part.Header
+
+		mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+		if err == nil && strings.HasPrefix(mediaType, "multipart/") && depth < maxPartDepth {
+			newBody, stripped, err := stripMultipart(partBody, params["boundary"], match, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			partBody = newBody
+			total += stripped
+		} else {
+			leaves, err := collectParts(header, partBody, maxPartDepth)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			if len(leaves) == 1 && match(&leaves[0]) {
+				header, partBody = strippedPart(&leaves[0])
+				total++
+			}
+		}
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to create MIME part: %w", err)
+		}
+
+		if _, err := w.Write(partBody); err != nil {
+			return nil, 0, fmt.Errorf("failed to write MIME part: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to finish multipart body: %w", err)
+	}
+
+	return buf.Bytes(), total, nil
+}
+
+func strippedPart(p *Part) (textproto.MIMEHeader, []byte) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "8bit")
+
+	note := fmt.Sprintf("[Removed %s part", p.ContentType)
+	if filename := p.Filename(); filename != "" {
+		note += fmt.Sprintf(" %q", filename)
+	}
+	note += fmt.Sprintf(" (%d bytes).]\r\n", len(p.Body))
+
+	return header, []byte(note)
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+const multipartMessage = "Subject: Invoice\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"XYZ\"\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"See attachment.\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--XYZ--\r\n"
+
+func TestStripParts(t *testing.T) {
+	msg, err := ParseMessage([]byte(multipartMessage))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	stripped, err := msg.StripParts(func(p *Part) bool {
+		matched, _ := path.Match("application/*", p.ContentType)
+		return matched
+	})
+	if err != nil {
+		t.Fatalf("Failed to strip parts: %v", err)
+	}
+
+	if stripped != 1 {
+		t.Fatalf("Expected 1 part to be stripped, got %d.", stripped)
+	}
+
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatalf("Failed to parse parts: %v", err)
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d.", len(parts))
+	}
+
+	if body := string(parts[0].Body); body != "See attachment." {
+		t.Errorf("Expected first part to be retained, got %q.", body)
+	}
+
+	if body := string(parts[1].Body); !strings.Contains(body, `Removed application/pdf part "invoice.pdf" (9 bytes)`) {
+		t.Errorf("Expected second part to be replaced, got %q.", body)
+	}
+
+	if !strings.Contains(string(msg.Raw()), "Subject: Invoice\r\n") {
+		t.Error("Expected headers to be retained.")
+	}
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+// maxPartDepth limits how deeply nested multipart bodies are parsed.
+const maxPartDepth = 10
+
+// Part is a single, non-multipart MIME part of a message.
+type Part struct {
+	Header      textproto.MIMEHeader
+	ContentType string
+	Params      map[string]string
+	// Body is the decoded (base64/quoted-printable) content of the part.
+	Body []byte
+}
+
+// Filename returns the name of an attachment, if any.
+func (p *Part) Filename() string {
+	if _, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return params["filename"]
+	}
+
+	return p.Params["name"]
+}
+
+// Parts returns all leaf parts of the message in document order. Messages
+// that are not multipart consist of a single part.
+func (m *Message) Parts() ([]Part, error) {
+	header := textproto.MIMEHeader(m.Header)
+
+	return collectParts(header, []byte(m.Body), 0)
+}
+
+func collectParts(header textproto.MIMEHeader, body []byte, depth int) ([]Part, error) {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// treat broken content types like plain text, to at least have a chance
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") || depth >= maxPartDepth {
+		decoded, err := decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Part{{
+			Header:      header,
+			ContentType: mediaType,
+			Params:      params,
+			Body:        decoded,
+		}}, nil
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	var parts []Part
+
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed going through the MIME parts: %w", err)
+		}
+
+		partBody, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MIME body: %w", err)
+		}
+
+		children, err := collectParts(part.Header, partBody, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, children...)
+	}
+
+	return parts, nil
+}
+
+func decodeTransferEncoding(encoding string, body []byte) ([]byte, error) {
+	switch strings.ToUpper(strings.TrimSpace(encoding)) {
+	case "BASE64":
+		// base64 bodies are usually wrapped
+		cleaned := bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, body)
+
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(cleaned)))
+		n, err := base64.StdEncoding.Decode(decoded, cleaned)
+		if err != nil {
+			return nil, fmt.Errorf("failed to base64 decode MIME body: %w", err)
+		}
+
+		return decoded[:n], nil
+
+	case "QUOTED-PRINTABLE":
+		decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode quoted-printable MIME body: %w", err)
+		}
+
+		return decoded, nil
+	}
+
+	return body, nil
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+var receivedIPRegex = regexp.MustCompile(`\[(?:IPv6:)?([0-9a-fA-F:.]+)\]`)
+
+// GetRelayIP returns the IP of the server that handed the message to our
+// infrastructure, i.e. the first public IP in the "from" clause of the
+// Received headers (newest first). Private and loopback addresses are skipped,
+// as they belong to local relays. An empty string is returned if no IP could
+// be found. The search stops at the first hop without an IP, as all older
+// Received headers could have been forged by the sender.
+func (m *Message) GetRelayIP() string {
+	for _, received := range m.Header["Received"] {
+		from := received
+
+		// only look at the sending side of the hop
+		if idx := strings.Index(strings.ToLower(from), " by "); idx >= 0 {
+			from = from[:idx]
+		}
+
+		local := false
+
+		for _, match := range receivedIPRegex.FindAllStringSubmatch(from, -1) {
+			addr, err := netip.ParseAddr(match[1])
+			if err != nil {
+				continue
+			}
+
+			addr = addr.Unmap()
+			if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+				local = true
+				continue
+			}
+
+			return addr.String()
+		}
+
+		if !local {
+			return ""
+		}
+	}
+
+	return ""
+}
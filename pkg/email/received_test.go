@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"testing"
+)
+
+func TestGetRelayIP(t *testing.T) {
+	testcases := []struct {
+		name     string
+		received []string
+		expected string
+	}{
+		{
+			name:     "no headers",
+			expected: "",
+		},
+		{
+			name: "single hop",
+			received: []string{
+				`from [192.30.252.201] (out-18.smtp.github.com) by mailserver.example.com (chasquid) with ESMTPS; Sat, 17 Feb 2024 15:20:18 +0000`,
+			},
+			expected: "192.30.252.201",
+		},
+		{
+			name: "local relay is skipped",
+			received: []string{
+				`from localhost (localhost [127.0.0.1]) by mail.example.com; Sat, 17 Feb 2024 15:20:19 +0000`,
+				`from internal.example.com (internal [10.0.0.5]) by localhost; Sat, 17 Feb 2024 15:20:18 +0000`,
+				`from mx.sender.example (mx.sender.example [IPv6:2001:db8::25]) by internal.example.com; Sat, 17 Feb 2024 15:20:17 +0000`,
+			},
+			expected: "2001:db8::25",
+		},
+		{
+			name: "receiving side is ignored",
+			received: []string{
+				`from unknown by mail.example.com [198.51.100.1]; Sat, 17 Feb 2024 15:20:18 +0000`,
+			},
+			expected: "",
+		},
+		{
+			name: "older hops are not trusted",
+			received: []string{
+				`from unknown by mail.example.com; Sat, 17 Feb 2024 15:20:18 +0000`,
+				`from forged.example (forged.example [203.0.113.9]) by unknown; Sat, 17 Feb 2024 15:20:17 +0000`,
+			},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := ParseMessage([]byte(testMessage))
+			if err != nil {
+				t.Fatalf("Failed to parse message: %v", err)
+			}
+
+			msg.Header["Received"] = tc.received
+
+			if ip := msg.GetRelayIP(); ip != tc.expected {
+				t.Fatalf("Expected %q, got %q.", tc.expected, ip)
+			}
+		})
+	}
+}
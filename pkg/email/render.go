@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"bytes"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"sort"
+)
+
+type rawHeaderField struct {
+	key   string
+	lines []byte
+}
+
+// render turns the message back into its wire format. Header fields that
+// were not modified since parsing are kept byte-for-byte, modified fields
+// are rewritten in place and new fields are prepended (like trace headers).
+func (m *Message) render() []byte {
+	newline := []byte("\r\n")
+	if m.raw != nil && !bytes.Contains(m.raw, newline) {
+		newline = []byte("\n")
+	}
+
+	fields := splitRawHeader(m.raw)
+	known := map[string]bool{}
+	for _, field := range fields {
+		known[field.key] = true
+	}
+
+	var buf bytes.Buffer
+
+	// prepend new headers
+	var added []string
+	for key := range m.Header {
+		if !known[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	for _, key := range added {
+		writeHeaderField(&buf, key, m.Header[key], newline)
+	}
+
+	// retain the original order for all other headers
+	written := map[string]bool{}
+	for _, field := range fields {
+		values, exists := m.Header[field.key]
+		if !exists {
+			continue
+		}
+
+		if slices.Equal(values, m.original[field.key]) {
+			buf.Write(field.lines)
+			continue
+		}
+
+		if !written[field.key] {
+			writeHeaderField(&buf, field.key, values, newline)
+			written[field.key] = true
+		}
+	}
+
+	buf.Write(newline)
+	buf.WriteString(m.Body)
+
+	return buf.Bytes()
+}
+
+func (m *Message) modified() bool {
+	if m.raw == nil || m.Body != m.originalBody || len(m.Header) != len(m.original) {
+		return true
+	}
+
+	for key, values := range m.Header {
+		if !slices.Equal(values, m.original[key]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeHeaderField(buf *bytes.Buffer, key string, values []string, newline []byte) {
+	for _, value := range values {
+		buf.WriteString(key)
+		buf.WriteString(": ")
+		buf.WriteString(value)
+		buf.Write(newline)
+	}
+}
+
+// splitRawHeader returns the header fields of a raw message, including
+// their continuation lines and line endings.
+func splitRawHeader(raw []byte) []rawHeaderField {
+	var fields []rawHeaderField
+
+	for len(raw) > 0 {
+		end := bytes.IndexByte(raw, '\n')
+		if end < 0 {
+			end = len(raw) - 1
+		}
+
+		line := raw[:end+1]
+
+		// end of header block
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			break
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].lines = append(fields[len(fields)-1].lines, line...)
+		} else if colon := bytes.IndexByte(line, ':'); colon > 0 {
+			fields = append(fields, rawHeaderField{
+				key:   textproto.CanonicalMIMEHeaderKey(string(bytes.TrimSpace(line[:colon]))),
+				lines: slices.Clone(line),
+			})
+		}
+
+		raw = raw[end+1:]
+	}
+
+	return fields
+}
+
+func cloneHeader(h mail.Header) mail.Header {
+	clone := make(mail.Header, len(h))
+	for key, values := range h {
+		clone[key] = slices.Clone(values)
+	}
+
+	return clone
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"testing"
+)
+
+const testMessage = "Received: from a\r\n  by b\r\nSubject: Hello\r\nX-Foo: one\r\nX-Foo: two\r\nFrom: me@example.com\r\n\r\nbody\r\n"
+
+func TestRaw(t *testing.T) {
+	testcases := []struct {
+		name     string
+		modify   func(m *Message)
+		expected string
+	}{
+		{
+			name:     "unmodified",
+			modify:   func(m *Message) {},
+			expected: testMessage,
+		},
+		{
+			name: "new header",
+			modify: func(m *Message) {
+				m.Header["Delivered-To"] = []string{"me"}
+			},
+			expected: "Delivered-To: me\r\n" + testMessage,
+		},
+		{
+			name: "changed header",
+			modify: func(m *Message) {
+				m.Header["Subject"] = []string{"[tag] Hello"}
+			},
+			expected: "Received: from a\r\n  by b\r\nSubject: [tag] Hello\r\nX-Foo: one\r\nX-Foo: two\r\nFrom: me@example.com\r\n\r\nbody\r\n",
+		},
+		{
+			name: "removed multi-value header",
+			modify: func(m *Message) {
+				delete(m.Header, "X-Foo")
+			},
+			expected: "Received: from a\r\n  by b\r\nSubject: Hello\r\nFrom: me@example.com\r\n\r\nbody\r\n",
+		},
+		{
+			name: "changed body",
+			modify: func(m *Message) {
+				m.Body = "new body\r\n"
+			},
+			expected: "Received: from a\r\n  by b\r\nSubject: Hello\r\nX-Foo: one\r\nX-Foo: two\r\nFrom: me@example.com\r\n\r\nnew body\r\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			msg, err := ParseMessage([]byte(testMessage))
+			if err != nil {
+				t.Fatalf("Failed to parse message: %v", err)
+			}
+
+			testcase.modify(msg)
+
+			if rendered := string(msg.Raw()); rendered != testcase.expected {
+				t.Fatalf("Expected\n%q\ngot\n%q", testcase.expected, rendered)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+// This is an external test package, as the header names come from packages
+// that import the email package themselves.
+package email_test
+
+import (
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+)
+
+const roundTripMessage = "Received: from a\r\n  by b\r\nSubject: Hello\r\nX-Foo: one\r\nFrom: me@example.com\r\n\r\nbody\r\n"
+
+func TestRawRoundTrip(t *testing.T) {
+	msg, err := email.ParseMessage([]byte(roundTripMessage))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	// headers spelled exactly as the processors write them during delivery
+	added := map[string]string{
+		"Delivered-To":        "me",
+		spam.VerdictHeader:    "status:maybe-spam,score:7.0,rule:test",
+		spam.StatusHeader:     "Yes, score=7.0",
+		"X-Rudi-LDA-Muted":    "root@example.com",
+		"X-Rudi-LDA-Released": "Mon, 02 Jan 2024 15:04:05 +0000",
+	}
+
+	for key, value := range added {
+		msg.SetHeader(key, value)
+	}
+
+	msg.SetSubject("[tag] Hello")
+	msg.DelHeader("X-Foo")
+
+	parsed, err := email.ParseMessage(msg.Raw())
+	if err != nil {
+		t.Fatalf("Failed to parse rendered message: %v", err)
+	}
+
+	for key, value := range added {
+		if got := parsed.Header.Get(key); got != value {
+			t.Errorf("Expected %s to be persisted as %q, got %q.", key, value, got)
+		}
+	}
+
+	if verdict := spam.ParseVerdict(parsed.Header.Get(spam.VerdictHeader)); verdict == nil || verdict.Status != spam.MaybeSpam {
+		t.Errorf("Expected maybe-spam verdict to be persisted, got %+v.", verdict)
+	}
+
+	if got := parsed.GetSubject(); got != "[tag] Hello" {
+		t.Errorf("Expected changed subject to be persisted, got %q.", got)
+	}
+
+	if got := parsed.Header.Get("X-Foo"); got != "" {
+		t.Errorf("Removed header should not have been persisted, got %q.", got)
+	}
+
+	if got := parsed.Header.Get("Received"); got != "from a\r\n  by b" && got != "from a by b" {
+		t.Errorf("Expected unmodified header to be kept, got %q.", got)
+	}
+
+	if parsed.Body != msg.Body {
+		t.Errorf("Expected body %q, got %q.", msg.Body, parsed.Body)
+	}
+
+	// rendering the parsed message again must not change anything
+	if string(parsed.Raw()) != string(msg.Raw()) {
+		t.Errorf("Expected rendering to be stable, got\n%q\nand\n%q", msg.Raw(), parsed.Raw())
+	}
+}
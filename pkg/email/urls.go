@@ -0,0 +1,255 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxURLs limits how many URLs are extracted from a single message.
+const maxURLs = 500
+
+// URL is a link found in the message body.
+type URL struct {
+	URL    string `json:"url"`
+	Scheme string `json:"scheme"`
+	Host   string `json:"host"`
+	// Domain is the registrable domain of the host (e.g. "example.co.uk" for
+	// "www.example.co.uk"), or the host itself for IPs.
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	// Text is the visible anchor text for links in HTML parts.
+	Text string `json:"text"`
+	// TextDomain is the registrable domain the anchor text shows, if any.
+	TextDomain string `json:"textDomain"`
+	// TextMismatch is true if the anchor text shows a different domain than
+	// the link actually points to.
+	TextMismatch bool `json:"textMismatch"`
+	// IPHost is true if the host is an IP literal, including obfuscated
+	// forms like "http://3232235777/".
+	IPHost bool `json:"ipHost"`
+	// Shortener is true if the host is a known URL shortener.
+	Shortener bool `json:"shortener"`
+}
+
+var (
+	textURLRegex = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'()\[\]{}]+`)
+	// textDomainRegex matches anchor texts that consist of a URL or domain
+	textDomainRegex  = regexp.MustCompile(`(?i)^(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})\.?(?:[:/?#]\S*)?$`)
+	numericHostRegex = regexp.MustCompile(`(?i)^(?:0x[0-9a-f]+|[0-9]+)(?:\.(?:0x[0-9a-f]+|[0-9]+)){0,3}$`)
+)
+
+var shorteners = map[string]struct{}{
+	"bit.ly":      {},
+	"bit.do":      {},
+	"buff.ly":     {},
+	"cutt.ly":     {},
+	"goo.gl":      {},
+	"is.gd":       {},
+	"ow.ly":       {},
+	"rb.gy":       {},
+	"rebrand.ly":  {},
+	"s.id":        {},
+	"shorturl.at": {},
+	"t.co":        {},
+	"t.ly":        {},
+	"tiny.cc":     {},
+	"tinyurl.com": {},
+	"v.gd":        {},
+}
+
+// URLs returns all http(s) links found in the text and HTML parts of the
+// message, in document order. Links in HTML parts include their anchor text.
+func (m *Message) URLs() ([]URL, error) {
+	parts, err := m.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	collector := &urlCollector{
+		seen: map[string]struct{}{},
+	}
+
+	for _, part := range parts {
+		switch part.ContentType {
+		case "text/plain":
+			collector.addText(string(part.Body))
+		case "text/html":
+			collector.addHTML(part.Body)
+		}
+	}
+
+	return collector.urls, nil
+}
+
+type urlCollector struct {
+	urls []URL
+	seen map[string]struct{}
+}
+
+func (c *urlCollector) addText(text string) {
+	for _, match := range textURLRegex.FindAllString(text, -1) {
+		c.add(match, "")
+	}
+}
+
+func (c *urlCollector) addHTML(body []byte) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	var (
+		inAnchor   bool
+		href       string
+		anchorText strings.Builder
+	)
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() != io.EOF {
+				return
+			}
+
+			if inAnchor {
+				c.add(href, anchorText.String())
+			}
+
+			return
+
+		case html.StartTagToken:
+			token := tokenizer.Token()
+			if token.Data != "a" {
+				continue
+			}
+
+			// unclosed anchors end at the next anchor
+			if inAnchor {
+				c.add(href, anchorText.String())
+			}
+
+			inAnchor = true
+			href = ""
+			anchorText.Reset()
+
+			for _, attr := range token.Attr {
+				if attr.Key == "href" {
+					href = attr.Val
+				}
+			}
+
+		case html.EndTagToken:
+			if token := tokenizer.Token(); token.Data == "a" && inAnchor {
+				c.add(href, anchorText.String())
+				inAnchor = false
+			}
+
+		case html.TextToken:
+			text := string(tokenizer.Text())
+
+			if inAnchor {
+				anchorText.WriteString(text)
+			} else {
+				c.addText(text)
+			}
+		}
+	}
+}
+
+func (c *urlCollector) add(rawURL string, text string) {
+	if len(c.urls) >= maxURLs {
+		return
+	}
+
+	rawURL = strings.TrimRight(strings.TrimSpace(rawURL), ".,;:!?")
+	text = strings.Join(strings.Fields(text), " ")
+
+	if strings.HasPrefix(strings.ToLower(rawURL), "www.") {
+		rawURL = "http://" + rawURL
+	}
+
+	key := rawURL + "\x00" + text
+	if _, ok := c.seen[key]; ok {
+		return
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return
+	}
+
+	c.seen[key] = struct{}{}
+
+	u := URL{
+		URL:       rawURL,
+		Scheme:    scheme,
+		Host:      host,
+		Domain:    RegistrableDomain(host),
+		Path:      parsed.Path,
+		Text:      text,
+		IPHost:    IsIPHost(host),
+		Shortener: IsShortener(host),
+	}
+
+	if match := textDomainRegex.FindStringSubmatch(text); match != nil {
+		u.TextDomain = RegistrableDomain(strings.ToLower(match[1]))
+		u.TextMismatch = u.TextDomain != u.Domain
+	}
+
+	c.urls = append(c.urls, u)
+}
+
+// RegistrableDomain returns the domain that was registered with a registrar,
+// i.e. the public suffix plus one label. IPs and hosts that are themselves
+// public suffixes are returned unchanged.
+func RegistrableDomain(host string) string {
+	host = strings.Trim(strings.ToLower(host), ".")
+
+	if IsIPHost(host) {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+
+	return domain
+}
+
+// IsIPHost returns true if the host is an IP literal. This includes the
+// numeric forms browsers accept, like "3232235777" or "0xC0.0xA8.0x00.0x01".
+func IsIPHost(host string) bool {
+	host = strings.Trim(host, "[]")
+
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+
+	return numericHostRegex.MatchString(host)
+}
+
+// IsShortener returns true if the host belongs to a known URL shortener.
+func IsShortener(host string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+
+	_, ok := shorteners[host]
+	return ok
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package email
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestURLs(t *testing.T) {
+	content, err := os.ReadFile("testdata/phishing.eml")
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+
+	msg, err := ParseMessage(content)
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	urls, err := msg.URLs()
+	if err != nil {
+		t.Fatalf("Failed to extract URLs: %v", err)
+	}
+
+	expected := []URL{
+		{
+			URL:    "https://login.paypa1-secure.com/verify?id=1",
+			Scheme: "https",
+			Host:   "login.paypa1-secure.com",
+			Domain: "paypa1-secure.com",
+			Path:   "/verify",
+		},
+		{
+			URL:    "http://www.example.org/help",
+			Scheme: "http",
+			Host:   "www.example.org",
+			Domain: "example.org",
+			Path:   "/help",
+		},
+		{
+			URL:          "https://login.paypa1-secure.com/verify?id=1",
+			Scheme:       "https",
+			Host:         "login.paypa1-secure.com",
+			Domain:       "paypa1-secure.com",
+			Path:         "/verify",
+			Text:         "https://www.paypal.com/signin",
+			TextDomain:   "paypal.com",
+			TextMismatch: true,
+		},
+		{
+			URL:    "http://3232235777/track",
+			Scheme: "http",
+			Host:   "3232235777",
+			Domain: "3232235777",
+			Path:   "/track",
+			Text:   "Track your parcel",
+			IPHost: true,
+		},
+		{
+			URL:          "https://bit.ly/3xyz",
+			Scheme:       "https",
+			Host:         "bit.ly",
+			Domain:       "bit.ly",
+			Path:         "/3xyz",
+			Text:         "www.example.co.uk",
+			TextDomain:   "example.co.uk",
+			TextMismatch: true,
+			Shortener:    true,
+		},
+		{
+			URL:    "https://shop.example.co.uk/offers",
+			Scheme: "https",
+			Host:   "shop.example.co.uk",
+			Domain: "example.co.uk",
+			Path:   "/offers",
+		},
+	}
+
+	if !cmp.Equal(expected, urls) {
+		t.Fatalf("Unexpected URLs:\n%s", cmp.Diff(expected, urls))
+	}
+}
+
+func TestIsIPHost(t *testing.T) {
+	testcases := map[string]bool{
+		"192.0.2.1":           true,
+		"[2001:db8::1]":       true,
+		"3232235777":          true,
+		"0xC0.0xA8.0x00.0x01": true,
+		"0300.0250.0.1":       true,
+		"example.com":         false,
+		"123.example.com":     false,
+	}
+
+	for host, expected := range testcases {
+		if IsIPHost(host) != expected {
+			t.Errorf("Expected IsIPHost(%q) = %v.", host, expected)
+		}
+	}
+}
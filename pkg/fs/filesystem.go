@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
@@ -17,9 +18,17 @@ var (
 	DirectoryPermissions os.FileMode = 0770
 )
 
+// filenameCounter distinguishes files created by the same process within
+// the same second.
+var filenameCounter atomic.Int64
+
 func UniqueEmailFilename() string {
 	now := time.Now().UTC().Format("20060102_150405")
 
+	if n := filenameCounter.Add(1) - 1; n > 0 {
+		return fmt.Sprintf("%s_%d_%d.eml", now, os.Getpid(), n)
+	}
+
 	return fmt.Sprintf("%s_%d.eml", now, os.Getpid())
 }
 
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// LockFile acquires an exclusive advisory lock on the given file, creating it
+// if necessary. It blocks until the lock can be acquired. The returned function
+// must be called to release the lock again.
+func LockFile(filename string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(filename), DirectoryPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, FilePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock: %w", err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// DotLock acquires a lock by exclusively creating filename + ".lock", the
+// convention used by Dovecot and other mail software for files that are
+// shared with them. Lock files older than staleTimeout are assumed to be
+// left over from crashed processes and are removed. If the lock cannot be
+// acquired within timeout, an error is returned. The returned function
+// must be called to release the lock again.
+func DotLock(filename string, timeout time.Duration, staleTimeout time.Duration) (func(), error) {
+	lockFile := filename + ".lock"
+
+	if err := os.MkdirAll(filepath.Dir(lockFile), DirectoryPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		f, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, FilePermissions)
+		if err == nil {
+			f.Close()
+
+			return func() {
+				_ = os.Remove(lockFile)
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if info, err := os.Stat(lockFile); err == nil && time.Since(info.ModTime()) > staleTimeout {
+			_ = os.Remove(lockFile)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", lockFile)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// WriteFileAtomic writes the data into a temporary file next to the
+// destination and then renames it, so that concurrent readers never
+// see a partially written file.
+func WriteFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+
+	if err := os.MkdirAll(dir, DirectoryPermissions); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := f.Chmod(FilePermissions); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return fmt.Errorf("failed to move temp file: %w", err)
+	}
+
+	return nil
+}
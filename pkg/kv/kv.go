@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+// Entry is a single value in the store.
+type Entry struct {
+	Value   any       `json:"value"`
+	Updated time.Time `json:"updated"`
+	// Expires is zero for entries that never expire.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+func (e Entry) Expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// Item is an entry together with its key.
+type Item struct {
+	Key string
+	Entry
+}
+
+type data struct {
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Store is a small file-backed key-value store that allows scripts to
+// remember things across deliveries. It is safe to be used by concurrent
+// processes. Expired entries are removed whenever the store is updated.
+type Store struct {
+	filename string
+	dryRun   bool
+
+	// memory holds all entries of in-memory stores and dry-run stores
+	// (once they have been modified).
+	lock   sync.Mutex
+	memory *data
+}
+
+// Filename returns the location of the store inside the datadir.
+func Filename(datadir string) string {
+	return filepath.Join(datadir, "kv.json")
+}
+
+// New returns a store backed by the given file. If the filename is empty,
+// the store only lives in memory.
+func New(filename string) *Store {
+	return &Store{
+		filename: filename,
+	}
+}
+
+// DryRun returns a store that reads the same file, but keeps all changes in
+// memory.
+func (s *Store) DryRun() *Store {
+	return &Store{
+		filename: s.filename,
+		dryRun:   true,
+	}
+}
+
+// Get returns the entry for the key, nil if it does not exist or has
+// expired.
+func (s *Store) Get(key string) (*Entry, error) {
+	d, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := d.Entries[key]
+	if !ok || entry.Expired(time.Now()) {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// Set stores a value. If ttl is zero, the value never expires.
+func (s *Store) Set(key string, value any, ttl time.Duration) error {
+	now := time.Now()
+
+	return s.update(func(d *data) {
+		d.Entries[key] = Entry{
+			Value:   value,
+			Updated: now,
+			Expires: expires(now, ttl),
+		}
+	})
+}
+
+// Increment increases the counter with the given key and returns its new
+// value. Counters are reset once their window has passed since the first
+// increment; if window is zero, counters are never reset.
+func (s *Store) Increment(key string, window time.Duration) (int64, error) {
+	now := time.Now()
+
+	var count int64
+
+	err := s.update(func(d *data) {
+		entry, ok := d.Entries[key]
+		if current, isInt := entry.Value.(int64); ok && isInt && !entry.Expired(now) {
+			count = current + 1
+		} else {
+			count = 1
+			entry.Expires = expires(now, window)
+		}
+
+		entry.Value = count
+		entry.Updated = now
+
+		d.Entries[key] = entry
+	})
+
+	return count, err
+}
+
+// SeenBefore remembers the key for the given ttl (zero means forever) and
+// returns whether it was already known.
+func (s *Store) SeenBefore(key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	var seen bool
+
+	err := s.update(func(d *data) {
+		entry, ok := d.Entries[key]
+		seen = ok && !entry.Expired(now)
+
+		d.Entries[key] = Entry{
+			Value:   true,
+			Updated: now,
+			Expires: expires(now, ttl),
+		}
+	})
+
+	return seen, err
+}
+
+// List returns all entries whose keys start with the prefix, sorted by
+// their keys.
+func (s *Store) List(prefix string) ([]Item, error) {
+	d, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	items := []Item{}
+
+	for key, entry := range d.Entries {
+		if strings.HasPrefix(key, prefix) && !entry.Expired(now) {
+			items = append(items, Item{Key: key, Entry: entry})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+
+	return items, nil
+}
+
+// Delete removes the given keys. Keys ending with "*" remove all keys with
+// that prefix. The removed keys are returned.
+func (s *Store) Delete(keys ...string) ([]string, error) {
+	var removed []string
+
+	err := s.update(func(d *data) {
+		for key := range d.Entries {
+			for _, pattern := range keys {
+				if key == pattern || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(key, strings.TrimSuffix(pattern, "*"))) {
+					removed = append(removed, key)
+					delete(d.Entries, key)
+					break
+				}
+			}
+		}
+	})
+
+	sort.Strings(removed)
+
+	return removed, err
+}
+
+func expires(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(ttl)
+}
+
+func (s *Store) inMemory() bool {
+	return s.filename == "" || s.dryRun
+}
+
+func (s *Store) read() (*data, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// return a copy, as the entries are modified by update() once the lock
+	// has been released
+	if s.memory != nil {
+		return &data{Entries: maps.Clone(s.memory.Entries)}, nil
+	}
+
+	return s.load()
+}
+
+func (s *Store) load() (*data, error) {
+	d := &data{
+		Entries: map[string]Entry{},
+	}
+
+	if s.filename == "" {
+		return d, nil
+	}
+
+	content, err := os.ReadFile(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+
+	// keep integers as integers, so that counters survive a roundtrip
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(d); err != nil {
+		return nil, fmt.Errorf("failed to decode store: %w", err)
+	}
+
+	if d.Entries == nil {
+		d.Entries = map[string]Entry{}
+	}
+
+	for key, entry := range d.Entries {
+		entry.Value = normalize(entry.Value)
+		d.Entries[key] = entry
+	}
+
+	return d, nil
+}
+
+func (s *Store) update(mutate func(d *data)) error {
+	if s.inMemory() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if s.memory == nil {
+			d, err := s.load()
+			if err != nil {
+				return err
+			}
+
+			s.memory = d
+		}
+
+		mutate(s.memory)
+		expire(s.memory)
+
+		return nil
+	}
+
+	unlock, err := fs.LockFile(s.filename + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock store: %w", err)
+	}
+	defer unlock()
+
+	d, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	mutate(d)
+	expire(d)
+
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	return fs.WriteFileAtomic(s.filename, encoded)
+}
+
+func expire(d *data) {
+	now := time.Now()
+
+	for key, entry := range d.Entries {
+		if entry.Expired(now) {
+			delete(d.Entries, key)
+		}
+	}
+}
+
+// normalize turns decoded JSON numbers into int64 or float64, like Rudi
+// uses them.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package kv
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "kv.json")
+	store := New(filename)
+
+	if err := store.Set("answer", int64(42), 0); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	if err := store.Set("object", map[string]any{"list": []any{int64(1), 1.5, "two"}}, time.Hour); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	if err := store.Set("expired", "foo", time.Nanosecond); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	// a fresh store must read the same values from the file
+	store = New(filename)
+
+	testcases := []struct {
+		key      string
+		expected any
+	}{
+		{key: "answer", expected: int64(42)},
+		{key: "object", expected: map[string]any{"list": []any{int64(1), 1.5, "two"}}},
+		{key: "expired", expected: nil},
+		{key: "unknown", expected: nil},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.key, func(t *testing.T) {
+			entry, err := store.Get(testcase.key)
+			if err != nil {
+				t.Fatalf("Failed to get value: %v", err)
+			}
+
+			var value any
+			if entry != nil {
+				value = entry.Value
+			}
+
+			if !reflect.DeepEqual(value, testcase.expected) {
+				t.Fatalf("Expected %#v, got %#v.", testcase.expected, value)
+			}
+		})
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "kv.json"))
+
+	for i := int64(1); i <= 3; i++ {
+		count, err := store.Increment("counter", time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+
+		if count != i {
+			t.Fatalf("Expected %d, got %d.", i, count)
+		}
+	}
+
+	// once the window has passed, the counter starts over
+	for i := 0; i < 2; i++ {
+		count, err := store.Increment("short", time.Millisecond)
+		if err != nil {
+			t.Fatalf("Failed to increment: %v", err)
+		}
+
+		if count != 1 {
+			t.Fatalf("Expected counter to be reset, got %d.", count)
+		}
+
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestSeenBefore(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "kv.json"))
+
+	for _, expected := range []bool{false, true, true} {
+		seen, err := store.SeenBefore("sender", 0)
+		if err != nil {
+			t.Fatalf("Failed to check key: %v", err)
+		}
+
+		if seen != expected {
+			t.Fatalf("Expected %v, got %v.", expected, seen)
+		}
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "kv.json"))
+
+	if err := store.Set("real", "value", 0); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	dryRun := store.DryRun()
+
+	if err := dryRun.Set("fake", "value", 0); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	items, err := dryRun.List("")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("Expected the dry-run store to contain 2 entries, got %v.", items)
+	}
+
+	if entry, _ := store.Get("fake"); entry != nil {
+		t.Fatal("Dry-run should not have modified the store.")
+	}
+}
+
+func TestInMemoryConcurrently(t *testing.T) {
+	store := New("")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if err := store.Set(fmt.Sprintf("key-%d", i), int64(i), 0); err != nil {
+				t.Errorf("Failed to set value: %v", err)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := store.List(""); err != nil {
+				t.Errorf("Failed to list: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	items, err := store.List("")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(items) != 10 {
+		t.Fatalf("Expected 10 entries, got %v.", items)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "kv.json"))
+
+	for _, key := range []string{"flood:example.com", "flood:example.org", "seen:foo@example.com"} {
+		if err := store.Set(key, true, 0); err != nil {
+			t.Fatalf("Failed to set value: %v", err)
+		}
+	}
+
+	removed, err := store.Delete("flood:*", "unknown")
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+
+	expected := []string{"flood:example.com", "flood:example.org"}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("Expected %v to be removed, got %v.", expected, removed)
+	}
+
+	items, err := store.List("")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(items) != 1 || items[0].Key != "seen:foo@example.com" {
+		t.Fatalf("Expected only the seen key to remain, got %v.", items)
+	}
+}
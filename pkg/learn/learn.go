@@ -0,0 +1,352 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package learn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/bayes"
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/fs"
+	"go.xrstf.de/rudi-lda/pkg/lists"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+)
+
+type Class string
+
+const (
+	Spam Class = "spam"
+	Ham  Class = "ham"
+)
+
+// Entry is what is remembered about a learned message.
+type Entry struct {
+	MessageID string    `json:"messageID,omitempty"`
+	Class     Class     `json:"class"`
+	Learned   time.Time `json:"learned"`
+}
+
+type state struct {
+	// Messages maps the unique part of message filenames to what has been
+	// learned from them.
+	Messages map[string]Entry `json:"messages"`
+}
+
+type Options struct {
+	// JunkFolder contains the messages the user considers spam.
+	JunkFolder string
+	// IgnoredFolders are never learned from (e.g. Trash).
+	IgnoredFolders []string
+	// HamAge is the age after which messages in the inbox are considered
+	// ham. Zero disables learning from the inbox.
+	HamAge time.Duration
+	// Allowlist and Blocklist are the names of list files in the datadir
+	// that the senders of ham/spam messages are added to. Empty names
+	// disable the lists.
+	Allowlist string
+	Blocklist string
+}
+
+// Stats summarizes a learning run.
+type Stats struct {
+	Spam      int
+	Ham       int
+	Relearned int
+	Skipped   int
+}
+
+// Learner learns from the way the user sorts their Maildir: messages in
+// the Junk folder are spam, messages moved out of the Junk folder and old
+// messages in the inbox are ham. Every message is learned only once.
+type Learner struct {
+	maildir *maildir.Maildir
+	datadir string
+	opt     Options
+}
+
+func New(md *maildir.Maildir, datadir string, opt Options) *Learner {
+	return &Learner{
+		maildir: md,
+		datadir: datadir,
+		opt:     opt,
+	}
+}
+
+type lesson struct {
+	name    string
+	msg     *email.Message
+	class   Class
+	relearn bool
+}
+
+func (l *Learner) Run(logger logrus.FieldLogger) (*Stats, error) {
+	stateFile := filepath.Join(l.datadir, "learned.json")
+
+	// prevent concurrent runs from learning messages twice
+	unlock, err := fs.LockFile(stateFile + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock state: %w", err)
+	}
+	defer unlock()
+
+	s, err := loadState(stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	lessons, seen, stats, err := l.collect(logger, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.apply(lessons); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, lesson := range lessons {
+		s.Messages[lesson.name] = Entry{
+			MessageID: lesson.msg.GetMessageID(),
+			Class:     lesson.class,
+			Learned:   now,
+		}
+	}
+
+	// forget about messages that have been deleted
+	for name := range s.Messages {
+		if _, ok := seen[name]; !ok {
+			delete(s.Messages, name)
+		}
+	}
+
+	if err := saveState(stateFile, s); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (l *Learner) collect(logger logrus.FieldLogger, s *state) ([]lesson, map[string]struct{}, *Stats, error) {
+	folders, err := l.maildir.Folders()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var lessons []lesson
+
+	seen := map[string]struct{}{}
+	stats := &Stats{}
+
+	for _, folder := range folders {
+		if l.ignored(folder) {
+			continue
+		}
+
+		files, err := l.maildir.List(folder)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		isJunk := folder == l.opt.JunkFolder
+
+		for _, file := range files {
+			name := maildir.UniqueName(file)
+			seen[name] = struct{}{}
+
+			entry, learned := s.Messages[name]
+
+			var class Class
+
+			switch {
+			case isJunk:
+				class = Spam
+			case learned && entry.Class == Spam:
+				// moved out of the Junk folder
+				class = Ham
+			case folder == "" && l.opt.HamAge > 0:
+				class = Ham
+			default:
+				continue
+			}
+
+			if learned && entry.Class == class {
+				continue
+			}
+
+			if !learned && class == Ham {
+				info, err := os.Stat(file)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+
+				if time.Since(info.ModTime()) < l.opt.HamAge {
+					continue
+				}
+			}
+
+			msg, err := l.maildir.Read(file)
+			if err != nil {
+				logger.WithError(err).WithField("file", file).Warn("Skipping unparseable message.")
+				stats.Skipped++
+				continue
+			}
+
+			// messages that we sorted into the Junk folder ourselves must be
+			// confirmed by the user (by reading them), otherwise we would only
+			// learn our own verdicts
+			if !learned && isJunk && deliveredAsJunk(msg) && !strings.Contains(maildir.Flags(file), "S") {
+				stats.Skipped++
+				continue
+			}
+
+			lessons = append(lessons, lesson{
+				name:    name,
+				msg:     msg,
+				class:   class,
+				relearn: learned,
+			})
+
+			switch {
+			case learned:
+				stats.Relearned++
+			case class == Spam:
+				stats.Spam++
+			default:
+				stats.Ham++
+			}
+		}
+	}
+
+	return lessons, seen, stats, nil
+}
+
+func (l *Learner) ignored(folder string) bool {
+	for _, ignored := range l.opt.IgnoredFolders {
+		if strings.EqualFold(folder, ignored) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliveredAsJunk returns true if the antispam processor has flagged the
+// message as spam or maybe-spam. This relies on the verdict header being
+// stored in the delivered file.
+func deliveredAsJunk(msg *email.Message) bool {
+	verdict := msg.Header.Get("X-Rudi-LDA-Antispam")
+
+	return strings.HasPrefix(verdict, "status:maybe-spam,") || strings.HasPrefix(verdict, "status:spam,")
+}
+
+func (l *Learner) apply(lessons []lesson) error {
+	if len(lessons) == 0 {
+		return nil
+	}
+
+	err := bayes.Update(bayes.Filename(l.datadir), func(db *bayes.DB) error {
+		for _, lesson := range lessons {
+			tokens := bayes.Tokenize(lesson.msg)
+
+			if lesson.relearn {
+				db.Untrain(tokens, lesson.class == Ham)
+			}
+
+			db.Train(tokens, lesson.class == Spam)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update classifier: %w", err)
+	}
+
+	var spammers, hammers []string
+
+	for _, lesson := range lessons {
+		from := lesson.msg.GetFrom()
+		if from == nil || from.Address == "" {
+			continue
+		}
+
+		if lesson.class == Spam {
+			spammers = append(spammers, strings.ToLower(from.Address))
+		} else {
+			hammers = append(hammers, strings.ToLower(from.Address))
+		}
+	}
+
+	if err := l.updateList(l.opt.Blocklist, spammers, hammers); err != nil {
+		return err
+	}
+
+	return l.updateList(l.opt.Allowlist, hammers, spammers)
+}
+
+func (l *Learner) updateList(name string, add []string, remove []string) error {
+	if name == "" {
+		return nil
+	}
+
+	filename, err := lists.Filename(lists.Directory(l.datadir), name)
+	if err != nil {
+		return err
+	}
+
+	if len(remove) > 0 {
+		if _, err := lists.Remove(filename, remove); err != nil {
+			return fmt.Errorf("failed to update list %s: %w", name, err)
+		}
+	}
+
+	if len(add) > 0 {
+		if _, err := lists.Add(filename, add); err != nil {
+			return fmt.Errorf("failed to update list %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func loadState(filename string) (*state, error) {
+	s := &state{
+		Messages: map[string]Entry{},
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+
+	if s.Messages == nil {
+		s.Messages = map[string]Entry{}
+	}
+
+	return s, nil
+}
+
+func saveState(filename string, s *state) error {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	return fs.WriteFileAtomic(filename, encoded)
+}
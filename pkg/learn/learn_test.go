@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package learn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/bayes"
+	"go.xrstf.de/rudi-lda/pkg/lists"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+)
+
+func writeMessage(t *testing.T, dir string, name string, from string, header string, age time.Duration) string {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	content := fmt.Sprintf("From: %s\r\nSubject: message %s\r\nMessage-ID: <%s@example.com>\r\n%s\r\nHello world, this is %s.\r\n", from, name, name, header, name)
+
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(filename, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func loadDB(t *testing.T, datadir string) *bayes.DB {
+	t.Helper()
+
+	db, err := bayes.Load(bayes.Filename(datadir))
+	if err != nil {
+		t.Fatalf("Failed to load classifier: %v", err)
+	}
+
+	return db
+}
+
+func TestLearner(t *testing.T) {
+	base := t.TempDir()
+	datadir := t.TempDir()
+	logger := logrus.New()
+
+	md, err := maildir.New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	day := 24 * time.Hour
+
+	writeMessage(t, filepath.Join(base, "cur"), "old:2,S", "friend@example.com", "", 30*day)
+	writeMessage(t, filepath.Join(base, "cur"), "recent:2,S", "friend@example.com", "", day)
+	writeMessage(t, filepath.Join(base, ".Junk", "cur"), "junk:2,", "spammer@example.net", "", day)
+	writeMessage(t, filepath.Join(base, ".Junk", "cur"), "flagged:2,", "other@example.net", "X-Rudi-LDA-Antispam: status:maybe-spam,rule:test\r\n", day)
+	writeMessage(t, filepath.Join(base, ".Junk", "cur"), "confirmed:2,S", "another@example.net", "X-Rudi-LDA-Antispam: status:maybe-spam,rule:test\r\n", day)
+	writeMessage(t, filepath.Join(base, ".Trash", "cur"), "trash:2,S", "whoever@example.net", "", 30*day)
+
+	learner := New(md, datadir, Options{
+		JunkFolder:     "Junk",
+		IgnoredFolders: []string{"Trash"},
+		HamAge:         14 * day,
+		Blocklist:      "blocked",
+		Allowlist:      "allowed",
+	})
+
+	stats, err := learner.Run(logger)
+	if err != nil {
+		t.Fatalf("Failed to learn: %v", err)
+	}
+
+	expected := Stats{Spam: 2, Ham: 1, Skipped: 1}
+	if *stats != expected {
+		t.Fatalf("Expected %+v, got %+v.", expected, *stats)
+	}
+
+	db := loadDB(t, datadir)
+	if db.Spam != 2 || db.Ham != 1 {
+		t.Fatalf("Expected 2 spam and 1 ham, got %d spam and %d ham.", db.Spam, db.Ham)
+	}
+
+	// learning again must not learn anything new
+	stats, err = learner.Run(logger)
+	if err != nil {
+		t.Fatalf("Failed to learn: %v", err)
+	}
+
+	if *stats != (Stats{Skipped: 1}) {
+		t.Fatalf("Expected nothing to be learned, got %+v.", *stats)
+	}
+
+	// the user moves a message out of the Junk folder
+	if err := md.Move(filepath.Join(base, ".Junk", "cur", "junk:2,"), "Archive"); err != nil {
+		t.Fatalf("Failed to move message: %v", err)
+	}
+
+	// and deletes an old message
+	if err := os.Remove(filepath.Join(base, "cur", "old:2,S")); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = learner.Run(logger)
+	if err != nil {
+		t.Fatalf("Failed to learn: %v", err)
+	}
+
+	if *stats != (Stats{Relearned: 1, Skipped: 1}) {
+		t.Fatalf("Expected 1 relearned message, got %+v.", *stats)
+	}
+
+	db = loadDB(t, datadir)
+	if db.Spam != 1 || db.Ham != 2 {
+		t.Fatalf("Expected 1 spam and 2 ham, got %d spam and %d ham.", db.Spam, db.Ham)
+	}
+
+	s, err := loadState(filepath.Join(datadir, "learned.json"))
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+
+	if _, ok := s.Messages["old"]; ok {
+		t.Error("Deleted message should have been forgotten.")
+	}
+
+	if entry := s.Messages["junk"]; entry.Class != Ham {
+		t.Errorf("Expected message to be remembered as ham, got %q.", entry.Class)
+	}
+
+	blocked, err := lists.Load(filepath.Join(lists.Directory(datadir), "blocked.txt"))
+	if err != nil {
+		t.Fatalf("Failed to load blocklist: %v", err)
+	}
+
+	if blocked.Contains("spammer@example.net") {
+		t.Error("Sender of relearned message should have been removed from the blocklist.")
+	}
+
+	if !blocked.Contains("another@example.net") {
+		t.Error("Sender of spam should be on the blocklist.")
+	}
+
+	allowed, err := lists.Load(filepath.Join(lists.Directory(datadir), "allowed.txt"))
+	if err != nil {
+		t.Fatalf("Failed to load allowlist: %v", err)
+	}
+
+	for _, sender := range []string{"friend@example.com", "spammer@example.net"} {
+		if !allowed.Contains(sender) {
+			t.Errorf("Sender %s of ham should be on the allowlist.", sender)
+		}
+	}
+}
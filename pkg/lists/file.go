@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package lists
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+// Extension is appended to list names that do not name an existing file.
+const Extension = ".txt"
+
+type cachedList struct {
+	modified int64
+	list     *List
+}
+
+var (
+	cacheLock sync.Mutex
+	cache     = map[string]cachedList{}
+)
+
+// Directory returns the directory inside the datadir where list files are
+// stored.
+func Directory(datadir string) string {
+	return filepath.Join(datadir, "lists")
+}
+
+// Find returns the filename for the given list name. The name is looked up
+// in every directory (in order), with and without the Extension. If the name
+// is an absolute path, it is returned as is.
+func Find(name string, directories ...string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+
+	for _, dir := range directories {
+		if dir == "" {
+			continue
+		}
+
+		for _, candidate := range []string{name, name + Extension} {
+			filename := filepath.Join(dir, candidate)
+
+			if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+				return filename, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("list %q not found", name)
+}
+
+// Filename returns the filename for a list name inside the given directory,
+// regardless of whether it exists.
+func Filename(directory string, name string) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+
+	if filepath.Ext(name) == "" {
+		name += Extension
+	}
+
+	return filepath.Join(directory, name), nil
+}
+
+func validateName(name string) error {
+	if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid list name %q", name)
+	}
+
+	return nil
+}
+
+// Load reads and parses a list file. Lists are cached until the file is
+// modified.
+func Load(filename string) (*List, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	modified := info.ModTime().UnixNano()
+
+	cacheLock.Lock()
+	cached, ok := cache[filename]
+	cacheLock.Unlock()
+
+	if ok && cached.modified == modified {
+		return cached.list, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid list %s: %w", filepath.Base(filename), err)
+	}
+
+	cacheLock.Lock()
+	cache[filename] = cachedList{
+		modified: modified,
+		list:     list,
+	}
+	cacheLock.Unlock()
+
+	return list, nil
+}
+
+// Add appends the entries to the list file, creating it if necessary.
+// Entries that are already part of the list are skipped. It returns the
+// entries that were actually added.
+func Add(filename string, entries []string) ([]string, error) {
+	var added []string
+
+	err := update(filename, func(lines []string) ([]string, error) {
+		existing := map[string]struct{}{}
+		for _, line := range lines {
+			existing[strings.ToLower(normalizeLine(line))] = struct{}{}
+		}
+
+		for _, entry := range entries {
+			entry = strings.TrimSpace(entry)
+			key := strings.ToLower(entry)
+
+			if _, ok := existing[key]; ok || entry == "" {
+				continue
+			}
+
+			// make sure the entry is valid before writing it
+			if err := validateEntry(entry); err != nil {
+				return nil, err
+			}
+
+			existing[key] = struct{}{}
+			lines = append(lines, entry)
+			added = append(added, entry)
+		}
+
+		return lines, nil
+	})
+
+	return added, err
+}
+
+// Remove removes the entries from the list file, leaving comments and all
+// other lines intact. It returns the entries that were actually removed.
+func Remove(filename string, entries []string) ([]string, error) {
+	var removed []string
+
+	err := update(filename, func(lines []string) ([]string, error) {
+		remove := map[string]struct{}{}
+		for _, entry := range entries {
+			remove[strings.ToLower(strings.TrimSpace(entry))] = struct{}{}
+		}
+
+		kept := make([]string, 0, len(lines))
+		for _, line := range lines {
+			entry := normalizeLine(line)
+
+			if _, ok := remove[strings.ToLower(entry)]; ok && entry != "" {
+				removed = append(removed, entry)
+				continue
+			}
+
+			kept = append(kept, line)
+		}
+
+		return kept, nil
+	})
+
+	return removed, err
+}
+
+func update(filename string, mutate func(lines []string) ([]string, error)) error {
+	unlock, err := fs.LockFile(filename + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock list: %w", err)
+	}
+	defer unlock()
+
+	var lines []string
+
+	content, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read list: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	lines, err = mutate(lines)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+
+	if err := fs.WriteFileAtomic(filename, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write list: %w", err)
+	}
+
+	return nil
+}
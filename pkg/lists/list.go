@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package lists
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// List is a parsed list file. Each line is one entry, empty lines and
+// comments (starting with "#") are ignored. Entries can be
+//
+//   - e-mail addresses ("spammer@example.com"), matched exactly,
+//   - domains ("example.com"), matching the domain and all of its subdomains,
+//   - IPs and CIDRs ("192.0.2.1", "198.51.100.0/24"),
+//   - regular expressions, enclosed in slashes ("/^noreply-\d+@/").
+//
+// All comparisons except regular expressions are case-insensitive.
+type List struct {
+	addresses map[string]string
+	domains   map[string]string
+	prefixes  []netip.Prefix
+	// prefixEntries contains the original entry for each prefix
+	prefixEntries []string
+	regexes       []*regexp.Regexp
+	entries       []string
+}
+
+// Parse reads a list from the given reader.
+func Parse(r io.Reader) (*List, error) {
+	list := newList()
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := normalizeLine(scanner.Text())
+		if entry == "" {
+			continue
+		}
+
+		if err := list.add(entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func newList() *List {
+	return &List{
+		addresses: map[string]string{},
+		domains:   map[string]string{},
+	}
+}
+
+// validateEntry returns an error if the entry cannot be parsed.
+func validateEntry(entry string) error {
+	return newList().add(entry)
+}
+
+// normalizeLine returns the entry in a line, without comments. Comments
+// start with a "#" at the beginning of the line or after whitespace, so
+// that regular expressions can still contain "#".
+func normalizeLine(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return strings.TrimSpace(line[:i])
+		}
+	}
+
+	return line
+}
+
+func (l *List) add(entry string) error {
+	l.entries = append(l.entries, entry)
+
+	if len(entry) > 1 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+		re, err := regexp.Compile(entry[1 : len(entry)-1])
+		if err != nil {
+			return fmt.Errorf("invalid expression %q: %w", entry, err)
+		}
+
+		l.regexes = append(l.regexes, re)
+		return nil
+	}
+
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		l.prefixes = append(l.prefixes, prefix.Masked())
+		l.prefixEntries = append(l.prefixEntries, entry)
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		l.prefixes = append(l.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		l.prefixEntries = append(l.prefixEntries, entry)
+		return nil
+	}
+
+	key := strings.ToLower(entry)
+
+	if strings.Contains(key, "@") {
+		l.addresses[key] = entry
+	} else {
+		l.domains[strings.Trim(key, ".")] = entry
+	}
+
+	return nil
+}
+
+// Entries returns all entries in the order they appear in the file.
+func (l *List) Entries() []string {
+	return l.entries
+}
+
+// Len returns the number of entries.
+func (l *List) Len() int {
+	return len(l.entries)
+}
+
+// Contains returns true if any entry matches the value.
+func (l *List) Contains(value string) bool {
+	_, ok := l.Match(value)
+	return ok
+}
+
+// Match returns the first entry that matches the value. Addresses match
+// address entries and the domain entries for their domain; IPs match IP and
+// CIDR entries; everything is matched against regular expressions.
+func (l *List) Match(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+
+	if addr, err := netip.ParseAddr(value); err == nil {
+		addr = addr.Unmap()
+
+		for i, prefix := range l.prefixes {
+			if prefix.Contains(addr) {
+				return l.prefixEntries[i], true
+			}
+		}
+	} else {
+		key := strings.ToLower(value)
+		domain := key
+
+		if idx := strings.LastIndex(key, "@"); idx >= 0 {
+			if entry, ok := l.addresses[key]; ok {
+				return entry, true
+			}
+
+			domain = key[idx+1:]
+		}
+
+		if entry, ok := l.matchDomain(domain); ok {
+			return entry, true
+		}
+	}
+
+	for _, re := range l.regexes {
+		if re.MatchString(value) {
+			return "/" + re.String() + "/", true
+		}
+	}
+
+	return "", false
+}
+
+// matchDomain checks the domain and all its parent domains.
+func (l *List) matchDomain(domain string) (string, bool) {
+	domain = strings.Trim(domain, ".")
+
+	for domain != "" {
+		if entry, ok := l.domains[domain]; ok {
+			return entry, true
+		}
+
+		idx := strings.Index(domain, ".")
+		if idx < 0 {
+			break
+		}
+
+		domain = domain[idx+1:]
+	}
+
+	return "", false
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package lists
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testList = `
+# senders
+Spammer@example.com
+ads.example.org  # tracking
+
+# networks
+192.0.2.1
+198.51.100.0/24
+2001:db8::/32
+
+/^noreply-\d+@/
+/^news#\d+@/	# issue numbers
+`
+
+func TestMatch(t *testing.T) {
+	list, err := Parse(strings.NewReader(testList))
+	if err != nil {
+		t.Fatalf("Failed to parse list: %v", err)
+	}
+
+	if list.Len() != 7 {
+		t.Fatalf("Expected 7 entries, got %d.", list.Len())
+	}
+
+	testcases := []struct {
+		value    string
+		expected string
+	}{
+		{value: "spammer@example.com", expected: "Spammer@example.com"},
+		{value: "someone@example.com"},
+		{value: "example.com"},
+		{value: "ads.example.org", expected: "ads.example.org"},
+		{value: "tracking.ADS.example.org", expected: "ads.example.org"},
+		{value: "someone@news.ads.example.org", expected: "ads.example.org"},
+		{value: "badads.example.org"},
+		{value: "192.0.2.1", expected: "192.0.2.1"},
+		{value: "192.0.2.2"},
+		{value: "198.51.100.77", expected: "198.51.100.0/24"},
+		{value: "::ffff:198.51.100.1", expected: "198.51.100.0/24"},
+		{value: "2001:db8::1", expected: "2001:db8::/32"},
+		{value: "noreply-1234@example.net", expected: `/^noreply-\d+@/`},
+		{value: "news#12@example.net", expected: `/^news#\d+@/`},
+		{value: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.value, func(t *testing.T) {
+			entry, ok := list.Match(tc.value)
+			if ok != (tc.expected != "") {
+				t.Fatalf("Expected match = %v, got %v (%q).", tc.expected != "", ok, entry)
+			}
+
+			if entry != tc.expected {
+				t.Fatalf("Expected %q to match, got %q.", tc.expected, entry)
+			}
+		})
+	}
+}
+
+func TestInvalidList(t *testing.T) {
+	if _, err := Parse(strings.NewReader("/[invalid/")); err == nil {
+		t.Fatal("Expected error for invalid expression, but got none.")
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "blocked.txt")
+
+	if err := os.WriteFile(filename, []byte("# my list\nexample.com # spam\n"), 0644); err != nil {
+		t.Fatalf("Failed to write list: %v", err)
+	}
+
+	added, err := Add(filename, []string{"EXAMPLE.com", "example.net", "192.0.2.0/24"})
+	if err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+
+	if len(added) != 2 {
+		t.Fatalf("Expected 2 added entries, got %v.", added)
+	}
+
+	list, err := Load(filename)
+	if err != nil {
+		t.Fatalf("Failed to load list: %v", err)
+	}
+
+	if !list.Contains("mail.example.net") || !list.Contains("192.0.2.9") {
+		t.Fatal("Expected added entries to be part of the list.")
+	}
+
+	removed, err := Remove(filename, []string{"example.com", "unknown.org"})
+	if err != nil {
+		t.Fatalf("Failed to remove entries: %v", err)
+	}
+
+	if len(removed) != 1 {
+		t.Fatalf("Expected 1 removed entry, got %v.", removed)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read list: %v", err)
+	}
+
+	expected := "# my list\nexample.net\n192.0.2.0/24\n"
+	if string(content) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, string(content))
+	}
+
+	if _, err := Add(filename, []string{"/[invalid/"}); err == nil {
+		t.Fatal("Expected error when adding invalid entry, but got none.")
+	}
+}
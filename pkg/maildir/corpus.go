@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// CorpusFiles returns all e-mail files in the given paths. Paths can be
+// files, plain directories or Maildir folders (with cur/ and new/).
+func CorpusFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		directories := []string{path}
+		if isMaildirFolder(path) {
+			directories = []string{filepath.Join(path, "cur"), filepath.Join(path, "new")}
+		}
+
+		for _, dir := range directories {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+
+				return nil, err
+			}
+
+			for _, entry := range entries {
+				if entry.Type().IsRegular() {
+					files = append(files, filepath.Join(dir, entry.Name()))
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+func isMaildirFolder(path string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if info, err := os.Stat(filepath.Join(path, sub)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+const (
+	// keywordsFile maps Maildir flag letters to IMAP keywords, as used by
+	// Dovecot.
+	keywordsFile = "dovecot-keywords"
+	// maxKeywords is the number of lowercase letters available as flags.
+	maxKeywords = 26
+
+	// keywordsLockTimeout and keywordsLockStale configure the dotlock that
+	// Dovecot (and we) hold while updating the keywords file.
+	keywordsLockTimeout = 10 * time.Second
+	keywordsLockStale   = 30 * time.Second
+)
+
+// KeywordFlag returns the Maildir flag (a lowercase letter) for the IMAP
+// keyword in the given folder. Unknown keywords are registered in the
+// folder's dovecot-keywords file, which is locked the same way Dovecot
+// locks it, so that concurrent deliveries and Dovecot itself do not
+// overwrite each other's keywords.
+func (m *Maildir) KeywordFlag(folder string, keyword string) (string, error) {
+	filename := filepath.Join(m.folderDirectory(folder), keywordsFile)
+
+	unlock, err := fs.DotLock(filename, keywordsLockTimeout, keywordsLockStale)
+	if err != nil {
+		return "", fmt.Errorf("failed to lock keywords: %w", err)
+	}
+	defer unlock()
+
+	keywords, err := readKeywords(filename)
+	if err != nil {
+		return "", err
+	}
+
+	used := map[int]bool{}
+	for idx, name := range keywords {
+		if name == keyword {
+			return keywordLetter(idx), nil
+		}
+
+		used[idx] = true
+	}
+
+	free := -1
+	for idx := 0; idx < maxKeywords; idx++ {
+		if !used[idx] {
+			free = idx
+			break
+		}
+	}
+
+	if free < 0 {
+		return "", errors.New("no free keyword slots left")
+	}
+
+	keywords[free] = keyword
+
+	if err := writeKeywords(filename, keywords); err != nil {
+		return "", err
+	}
+
+	return keywordLetter(free), nil
+}
+
+func keywordLetter(idx int) string {
+	return string(rune('a' + idx))
+}
+
+func readKeywords(filename string) (map[int]string, error) {
+	keywords := map[int]string{}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return keywords, nil
+		}
+
+		return nil, fmt.Errorf("failed to read keywords: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		index, name, found := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !found {
+			continue
+		}
+
+		idx, err := strconv.Atoi(index)
+		if err != nil || idx < 0 || idx >= maxKeywords {
+			continue
+		}
+
+		keywords[idx] = name
+	}
+
+	return keywords, nil
+}
+
+func writeKeywords(filename string, keywords map[int]string) error {
+	indexes := make([]int, 0, len(keywords))
+	for idx := range keywords {
+		indexes = append(indexes, idx)
+	}
+
+	sort.Ints(indexes)
+
+	var buf bytes.Buffer
+	for _, idx := range indexes {
+		fmt.Fprintf(&buf, "%d %s\n", idx, keywords[idx])
+	}
+
+	if err := fs.WriteFileAtomic(filename, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write keywords: %w", err)
+	}
+
+	return nil
+}
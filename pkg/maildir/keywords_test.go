@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeywordFlag(t *testing.T) {
+	base := t.TempDir()
+
+	md, err := New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	folder := filepath.Join(base, ".Junk")
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// keywords already known to Dovecot must be retained
+	if err := os.WriteFile(filepath.Join(folder, keywordsFile), []byte("0 $Forwarded\n2 NonJunk\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		keyword  string
+		expected string
+	}{
+		{keyword: "NonJunk", expected: "c"},
+		{keyword: "$Junk", expected: "b"},
+		{keyword: "$Junk", expected: "b"},
+		{keyword: "$Phishing", expected: "d"},
+	}
+
+	for _, testcase := range testcases {
+		flag, err := md.KeywordFlag("Junk", testcase.keyword)
+		if err != nil {
+			t.Fatalf("Failed to get flag for %s: %v", testcase.keyword, err)
+		}
+
+		if flag != testcase.expected {
+			t.Errorf("Expected flag %q for %s, got %q.", testcase.expected, testcase.keyword, flag)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(folder, keywordsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "0 $Forwarded\n1 $Junk\n2 NonJunk\n3 $Phishing\n"
+	if string(content) != expected {
+		t.Errorf("Expected keywords file\n%s\ngot\n%s", expected, string(content))
+	}
+}
+
+func TestKeywordFlagConcurrently(t *testing.T) {
+	base := t.TempDir()
+
+	md, err := New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	const count = 10
+
+	flags := make([]string, count)
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			flags[i], errs[i] = md.KeywordFlag("Junk", fmt.Sprintf("$Keyword%d", i))
+		}(i)
+	}
+
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for i, flag := range flags {
+		if errs[i] != nil {
+			t.Fatalf("Failed to get flag: %v", errs[i])
+		}
+
+		if seen[flag] {
+			t.Fatalf("Flag %q was assigned more than once: %v", flag, flags)
+		}
+
+		seen[flag] = true
+	}
+
+	keywords, err := readKeywords(filepath.Join(base, ".Junk", keywordsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keywords) != count {
+		t.Errorf("Expected %d keywords to be registered, got %v.", count, keywords)
+	}
+
+	if _, err := os.Stat(filepath.Join(base, ".Junk", keywordsFile+".lock")); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed, got %v.", err)
+	}
+}
+
+func TestKeywordFlagStaleLock(t *testing.T) {
+	base := t.TempDir()
+
+	md, err := New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	folder := filepath.Join(base, ".Junk")
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// a lock left behind by a crashed process
+	lockFile := filepath.Join(folder, keywordsFile+".lock")
+	if err := os.WriteFile(lockFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	abandoned := time.Now().Add(-2 * keywordsLockStale)
+	if err := os.Chtimes(lockFile, abandoned, abandoned); err != nil {
+		t.Fatal(err)
+	}
+
+	flag, err := md.KeywordFlag("Junk", "$Junk")
+	if err != nil {
+		t.Fatalf("Failed to get flag: %v", err)
+	}
+
+	if flag != "a" {
+		t.Errorf("Expected flag %q, got %q.", "a", flag)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
 	"go.xrstf.de/rudi-lda/pkg/fs"
@@ -31,11 +32,23 @@ func New(baseDir string) (*Maildir, error) {
 	}, nil
 }
 
+// UserDirectory returns the Maildir of a user (with or without domain)
+// inside the root directory that contains the Maildirs of all users.
+func UserDirectory(root string, user string) string {
+	parts := strings.Split(user, "@")
+
+	return filepath.Join(root, parts[0])
+}
+
 func (m *Maildir) Deliver(folder string, msg *email.Message) error {
-	destinationDir := m.baseDir
-	if folder != "" {
-		destinationDir = filepath.Join(destinationDir, "."+folder)
-	}
+	return m.DeliverWithFlags(folder, msg, "")
+}
+
+// DeliverWithFlags delivers a message with the given Maildir flags (e.g. "S"
+// for seen). Flagged messages are placed directly into cur/, since they have
+// already been "processed" by the mail client.
+func (m *Maildir) DeliverWithFlags(folder string, msg *email.Message, flags string) error {
+	destinationDir := m.folderDirectory(folder)
 
 	// create temporary file first
 	tmpFile, err := fs.WriteEmail(filepath.Join(destinationDir, "tmp"), msg)
@@ -43,28 +56,172 @@ func (m *Maildir) Deliver(folder string, msg *email.Message) error {
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
 
-	// ensure ./new exists
+	// ensure ./new and ./cur exist
 	newDir := filepath.Join(destinationDir, "new")
 	if err := os.MkdirAll(newDir, fs.DirectoryPermissions); err != nil {
 		return fmt.Errorf("failed to ensure new directory: %w", err)
 	}
 
-	// move file atomically to the new directory
+	curDir := filepath.Join(destinationDir, "cur")
+	if err := os.MkdirAll(curDir, fs.DirectoryPermissions); err != nil {
+		return fmt.Errorf("failed to ensure cur directory: %w", err)
+	}
+
+	// move file atomically to the new (or cur) directory
 	newFile := filepath.Join(newDir, filepath.Base(tmpFile))
+	if flags != "" {
+		newFile = filepath.Join(curDir, filepath.Base(tmpFile)+":2,"+flags)
+	}
+
 	if err := os.Rename(tmpFile, newFile); err != nil {
 		return fmt.Errorf("failed to move message to new directory: %w", err)
 	}
 
-	// Maildir folders need to be marked
-	if folder != "" {
-		markerFile := filepath.Join(destinationDir, "maildirfolder")
+	return m.markFolder(folder)
+}
 
-		if _, err := os.Stat(markerFile); err != nil {
-			if err := os.WriteFile(markerFile, nil, fs.FilePermissions); err != nil {
-				return fmt.Errorf("failed to mark: %w", err)
-			}
+// markFolder creates the marker file that Maildir++ folders need.
+func (m *Maildir) markFolder(folder string) error {
+	if folder == "" {
+		return nil
+	}
+
+	markerFile := filepath.Join(m.folderDirectory(folder), "maildirfolder")
+
+	if _, err := os.Stat(markerFile); err != nil {
+		if err := os.WriteFile(markerFile, nil, fs.FilePermissions); err != nil {
+			return fmt.Errorf("failed to mark: %w", err)
 		}
 	}
 
 	return nil
 }
+
+// List returns the paths of all messages in the given folder, both in new/
+// and cur/.
+func (m *Maildir) List(folder string) ([]string, error) {
+	var files []string
+
+	for _, sub := range []string{"new", "cur"} {
+		dir := filepath.Join(m.folderDirectory(folder), sub)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Move moves a message file (as returned by List) into another folder,
+// retaining its flags.
+func (m *Maildir) Move(file string, folder string) error {
+	destinationDir := m.folderDirectory(folder)
+
+	// new/ messages have no flags yet and belong into new/ again
+	sub := filepath.Base(filepath.Dir(file))
+	if sub != "new" {
+		sub = "cur"
+	}
+
+	// the target folder might not exist yet
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(destinationDir, dir), fs.DirectoryPermissions); err != nil {
+			return fmt.Errorf("failed to ensure %s directory: %w", dir, err)
+		}
+	}
+
+	if err := os.Rename(file, filepath.Join(destinationDir, sub, filepath.Base(file))); err != nil {
+		return fmt.Errorf("failed to move message: %w", err)
+	}
+
+	return m.markFolder(folder)
+}
+
+// Folders returns the names of all folders, including the inbox ("").
+func (m *Maildir) Folders() ([]string, error) {
+	entries, err := os.ReadDir(m.baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+
+	folders := []string{""}
+
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") && entry.Name() != "." && entry.Name() != ".." {
+			folders = append(folders, strings.TrimPrefix(entry.Name(), "."))
+		}
+	}
+
+	return folders, nil
+}
+
+// Find returns the path of the message with the given unique name (see
+// UniqueName) in any folder.
+func (m *Maildir) Find(name string) (string, error) {
+	folders, err := m.Folders()
+	if err != nil {
+		return "", err
+	}
+
+	for _, folder := range folders {
+		files, err := m.List(folder)
+		if err != nil {
+			return "", err
+		}
+
+		for _, file := range files {
+			if UniqueName(file) == name {
+				return file, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no message %q found", name)
+}
+
+// UniqueName returns the unique part of a message filename, which mail
+// clients retain when moving messages between folders.
+func UniqueName(file string) string {
+	name, _, _ := strings.Cut(filepath.Base(file), ":")
+	return name
+}
+
+// Flags returns the Maildir flags of a message file (e.g. "S" for seen).
+func Flags(file string) string {
+	_, info, found := strings.Cut(filepath.Base(file), ":2,")
+	if !found {
+		return ""
+	}
+
+	return info
+}
+
+func (m *Maildir) folderDirectory(folder string) string {
+	if folder == "" {
+		return m.baseDir
+	}
+
+	return filepath.Join(m.baseDir, "."+folder)
+}
+
+// Read parses a message file (as returned by List).
+func (m *Maildir) Read(file string) (*email.Message, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	return email.ParseMessage(content)
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	base := t.TempDir()
+
+	md, err := New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	files := []string{
+		filepath.Join(base, "new", "1700000000.inbox.host"),
+		filepath.Join(base, ".Lists.Go", "cur", "1700000001.golang.host:2,S"),
+	}
+
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(file, []byte("Subject: test\r\n\r\nBody\r\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range files {
+		found, err := md.Find(UniqueName(expected))
+		if err != nil {
+			t.Fatalf("Failed to find %s: %v", expected, err)
+		}
+
+		if found != expected {
+			t.Errorf("Expected %s, got %s.", expected, found)
+		}
+	}
+
+	if _, err := md.Find("1700000002.missing.host"); err == nil {
+		t.Error("Expected an error for a missing message.")
+	}
+}
+
+func TestMoveCreatesFolder(t *testing.T) {
+	base := t.TempDir()
+
+	md, err := New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	file := filepath.Join(base, "cur", "1700000000.inbox.host:2,S")
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(file, []byte("Subject: test\r\n\r\nBody\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := md.Move(file, "Archive"); err != nil {
+		t.Fatalf("Failed to move message: %v", err)
+	}
+
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if info, err := os.Stat(filepath.Join(base, ".Archive", dir)); err != nil || !info.IsDir() {
+			t.Errorf("Expected %s directory to exist: %v", dir, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(base, ".Archive", "cur", filepath.Base(file))); err != nil {
+		t.Errorf("Expected message to be moved: %v", err)
+	}
+}
@@ -5,65 +5,135 @@ package antispam
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
-	"go.xrstf.de/rudi-lda/pkg/fs"
 	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+	"go.xrstf.de/rudi-lda/pkg/quarantine"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
 	"go.xrstf.de/rudi-lda/pkg/spam"
 )
 
+// DefaultTag is prefixed to the subject of e-mails with the tag action.
+const DefaultTag = "[SPAM] "
+
 type Proc struct {
 	scriptFile string
-	backupDir  string
+	quarantine *quarantine.Quarantine
+	user       string
+	thresholds spam.Thresholds
+	policy     spam.Policy
+	tag        string
 }
 
-func New(scriptFile string, backupDir string) *Proc {
+func New(scriptFile string) *Proc {
 	return &Proc{
 		scriptFile: scriptFile,
-		backupDir:  backupDir,
+		thresholds: spam.DefaultThresholds,
+		policy:     spam.DefaultPolicy,
+		tag:        DefaultTag,
 	}
 }
 
+// WithQuarantine keeps discarded e-mails in the quarantine, so that they
+// can be released if they turn out to be false positives.
+func (p *Proc) WithQuarantine(q *quarantine.Quarantine, user string) *Proc {
+	p.quarantine = q
+	p.user = user
+
+	return p
+}
+
+// WithThresholds configures the scores at which e-mails are considered
+// maybe-spam and spam.
+func (p *Proc) WithThresholds(thresholds spam.Thresholds) *Proc {
+	p.thresholds = thresholds
+	return p
+}
+
+// WithPolicy configures what happens to spam and maybe-spam. Only the
+// discard, tag and spam actions are handled by this processor, the maildir
+// processor takes care of the others.
+func (p *Proc) WithPolicy(policy spam.Policy, tag string) *Proc {
+	p.policy = policy
+	p.tag = tag
+
+	return p
+}
+
 func (*Proc) Name() string {
 	return "antispam"
 }
 
 func (p *Proc) Process(ctx context.Context, logger logrus.FieldLogger, msg *email.Message, metrics *metrics.Metrics) (consumed bool, updated *email.Message, err error) {
-	result, err := spam.Check(ctx, p.scriptFile, msg)
+	// never trust verdicts from the outside
+	msg.DelHeader(spam.VerdictHeader)
+	msg.DelHeader(spam.StatusHeader)
+
+	result, err := spam.Check(ctx, p.scriptFile, msg, p.thresholds)
 	if err != nil {
+		if errors.Is(err, rudilib.ErrLimitExceeded) {
+			logger.WithError(err).Error("Spam script was aborted, delivering without spam check.")
+		}
+
 		return false, nil, err
 	}
 
 	if result == nil {
+		processor.Notef(ctx, "spam script made no decision")
 		return false, msg, nil
 	}
 
-	msg.Header["X-Rudi-LDA-Antispam"] = []string{
-		fmt.Sprintf("status:%s,rule:%s", result.Status, result.Rule),
+	processor.Notef(ctx, "spam script: %s", result.Header(p.thresholds))
+
+	if result.Status == spam.MaybeSpam && p.policy.Has(spam.MaybeSpam, spam.ActionSpam) {
+		result.Status = spam.Spam
 	}
 
-	logger = logger.WithField("rule", result.Rule)
+	msg.SetHeader(spam.VerdictHeader, result.Verdict())
+	msg.SetHeader(spam.StatusHeader, result.Header(p.thresholds))
 
-	if result.Status == spam.Spam {
-		logger.Info("Dropping spam.")
+	for _, rule := range result.Rules() {
+		metrics.SpamRules[rule]++
+	}
 
+	logger = logger.WithField("rule", result.Rule).WithField("score", result.Score)
+
+	if p.policy.Has(result.Status, spam.ActionDiscard) {
 		metrics.Discarded++
-		metrics.SpamRules[result.Rule]++
 
-		if p.backupDir != "" {
-			if _, err := fs.WriteEmail(p.backupDir, msg); err != nil {
-				logger.WithError(err).Error("Failed to backup spam e-mail: %w", err)
-				// if we cannot backup spam, we must deliver it to the inbodx to prevent data loss
+		if p.quarantine != nil {
+			var entry *quarantine.Entry
+
+			err := processor.GetEffects(ctx).Apply("quarantine e-mail", func() (err error) {
+				entry, err = p.quarantine.Add(msg, p.user, result.Rule, result.Score)
+				return err
+			})
+			if err != nil {
+				logger.WithError(err).Error("Failed to quarantine spam e-mail.")
+				// if we cannot quarantine spam, we must deliver it to the inbox to prevent data loss
 				return false, msg, nil
 			}
+
+			if entry != nil {
+				logger = logger.WithField("quarantine", entry.ID)
+			}
 		}
 
+		logger.Info("Dropping spam.")
+		processor.SetDestination(ctx, "(discarded)")
+
 		return true, nil, nil
 	}
 
+	if p.policy.Has(result.Status, spam.ActionTag) && !strings.HasPrefix(msg.GetSubject(), p.tag) {
+		msg.SetSubject(p.tag + msg.GetSubject())
+	}
+
 	logger.WithField("status", result.Status).Debug("Passed spamtest.")
 
 	return false, msg, nil
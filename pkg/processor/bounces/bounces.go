@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package bounces
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+)
+
+type Proc struct {
+	datadir  string
+	destUser string
+}
+
+func New(datadir string, destUser string) *Proc {
+	return &Proc{
+		datadir:  datadir,
+		destUser: destUser,
+	}
+}
+
+func (*Proc) Name() string {
+	return "bounces"
+}
+
+type record struct {
+	Time              time.Time `json:"time"`
+	Destination       string    `json:"destination"`
+	ReportingMTA      string    `json:"reportingMta,omitempty"`
+	OriginalMessageID string    `json:"originalMessageId,omitempty"`
+	Recipient         string    `json:"recipient"`
+	Action            string    `json:"action"`
+	Status            string    `json:"status"`
+	Diagnostic        string    `json:"diagnostic,omitempty"`
+}
+
+// Process appends all failed recipients of a bounce to $datadir/bounces.jsonl. Bounces
+// are not consumed, so the folder script can still sort them (using `.dsn`).
+func (p *Proc) Process(ctx context.Context, logger logrus.FieldLogger, msg *email.Message, _ *metrics.Metrics) (consumed bool, updated *email.Message, err error) {
+	dsn, err := msg.ParseDSN()
+	if err != nil {
+		return false, msg, fmt.Errorf("failed to parse DSN: %w", err)
+	}
+
+	if dsn == nil {
+		return false, msg, nil
+	}
+
+	// delivered, relayed, expanded and delayed recipients are no bounces
+	failed := dsn.Failed()
+	if len(failed) == 0 {
+		return false, msg, nil
+	}
+
+	logger.WithField("recipients", len(failed)).Info("Handling bounce.")
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	now := time.Now().UTC()
+
+	for _, recipient := range failed {
+		if err := encoder.Encode(record{
+			Time:              now,
+			Destination:       p.destUser,
+			ReportingMTA:      dsn.ReportingMTA,
+			OriginalMessageID: dsn.OriginalMessageID,
+			Recipient:         recipient.Recipient,
+			Action:            recipient.Action,
+			Status:            recipient.Status,
+			Diagnostic:        recipient.Diagnostic,
+		}); err != nil {
+			return false, msg, fmt.Errorf("failed to encode data: %w", err)
+		}
+	}
+
+	logFile := filepath.Join(p.datadir, "bounces.jsonl")
+	if err := processor.GetEffects(ctx).AppendFile(logFile, buf.Bytes()); err != nil {
+		return false, msg, fmt.Errorf("failed to append data: %w", err)
+	}
+
+	return false, msg, nil
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package bounces
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+)
+
+func TestProcess(t *testing.T) {
+	testcases := []struct {
+		filename string
+		expected []string
+	}{
+		{
+			filename: "mixed.eml",
+			expected: []string{"nobody@example.com"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.filename, func(t *testing.T) {
+			content, err := os.ReadFile("testdata/" + testcase.filename)
+			if err != nil {
+				t.Fatalf("Failed to read testdata: %v", err)
+			}
+
+			msg, err := email.ParseMessage(content)
+			if err != nil {
+				t.Fatalf("Failed to parse mail body: %v", err)
+			}
+
+			datadir := t.TempDir()
+
+			consumed, _, err := New(datadir, "me").Process(context.Background(), logrus.New(), msg, &metrics.Metrics{})
+			if err != nil {
+				t.Fatalf("Failed to process bounce: %v", err)
+			}
+
+			if consumed {
+				t.Fatal("Bounces should not be consumed.")
+			}
+
+			logged, err := os.ReadFile(filepath.Join(datadir, "bounces.jsonl"))
+			if err != nil {
+				t.Fatalf("Failed to read bounces log: %v", err)
+			}
+
+			var recipients []string
+
+			scanner := bufio.NewScanner(bytes.NewReader(logged))
+			for scanner.Scan() {
+				var r record
+				if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+					t.Fatalf("Failed to decode record: %v", err)
+				}
+
+				if r.Action != "failed" {
+					t.Errorf("Expected only failed recipients, got %+v.", r)
+				}
+
+				recipients = append(recipients, r.Recipient)
+			}
+
+			if len(recipients) != len(testcase.expected) {
+				t.Fatalf("Expected %v to be logged, got %v.", testcase.expected, recipients)
+			}
+
+			for i, recipient := range testcase.expected {
+				if recipients[i] != recipient {
+					t.Fatalf("Expected %v to be logged, got %v.", testcase.expected, recipients)
+				}
+			}
+		})
+	}
+}
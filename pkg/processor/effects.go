@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package processor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+// Effects performs all side effects of processors, like appending to data
+// files or delivering e-mails. Processors must not change anything outside
+// of the message itself without going through the Effects from their
+// context, so that a dry-run can skip them.
+type Effects interface {
+	// AppendFile appends data to a file, creating the file and its
+	// directory if needed.
+	AppendFile(filename string, data []byte) error
+	// Apply performs any other side effect. The description is meant for
+	// humans, e.g. "deliver into INBOX".
+	Apply(description string, fn func() error) error
+}
+
+type effectsKey struct{}
+
+// WithEffects returns a context that makes processors use the given Effects.
+func WithEffects(ctx context.Context, effects Effects) context.Context {
+	return context.WithValue(ctx, effectsKey{}, effects)
+}
+
+// GetEffects returns the Effects from the context, by default all side
+// effects are performed.
+func GetEffects(ctx context.Context) Effects {
+	if effects, ok := ctx.Value(effectsKey{}).(Effects); ok {
+		return effects
+	}
+
+	return direct{}
+}
+
+type direct struct {
+	trace *Trace
+}
+
+// NewEffects returns Effects that perform all side effects. If trace is not
+// nil, the side effects are also recorded in it.
+func NewEffects(trace *Trace) Effects {
+	return direct{trace: trace}
+}
+
+func (d direct) AppendFile(filename string, data []byte) error {
+	d.trace.effect(fmt.Sprintf("append %d bytes to %s", len(data), filename))
+
+	if err := os.MkdirAll(filepath.Dir(filename), fs.DirectoryPermissions); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fs.FilePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+
+	return err
+}
+
+func (d direct) Apply(description string, fn func() error) error {
+	d.trace.effect(description)
+
+	return fn()
+}
+
+type dryRun struct {
+	trace *Trace
+}
+
+// NewDryRun returns Effects that only record the side effects in the trace
+// (which can be nil) instead of performing them.
+func NewDryRun(trace *Trace) Effects {
+	return dryRun{trace: trace}
+}
+
+func (d dryRun) AppendFile(filename string, data []byte) error {
+	d.trace.effect(fmt.Sprintf("would append %d bytes to %s", len(data), filename))
+	return nil
+}
+
+func (d dryRun) Apply(description string, _ func() error) error {
+	d.trace.effect("would " + description)
+	return nil
+}
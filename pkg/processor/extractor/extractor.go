@@ -0,0 +1,275 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package extractor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+)
+
+type Proc struct {
+	datadir string
+	rules   []*Rule
+}
+
+func New(datadir string, rules []*Rule) *Proc {
+	return &Proc{
+		datadir: datadir,
+		rules:   rules,
+	}
+}
+
+func (*Proc) Name() string {
+	return "extractor"
+}
+
+func (p *Proc) Process(ctx context.Context, logger logrus.FieldLogger, msg *email.Message, _ *metrics.Metrics) (consumed bool, updated *email.Message, err error) {
+	for _, rule := range p.rules {
+		if !rule.Match.matches(msg) {
+			continue
+		}
+
+		logger := logger.WithField("rule", rule.Name)
+		logger.Info("Extracting data.")
+
+		values, err := rule.extract(msg)
+		if err != nil {
+			return false, msg, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+
+		if err := p.write(ctx, rule, values); err != nil {
+			return false, msg, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+
+		if !rule.Deliver {
+			return true, nil, nil
+		}
+	}
+
+	return false, msg, nil
+}
+
+func (p *Proc) write(ctx context.Context, rule *Rule, values []any) error {
+	var line string
+
+	switch rule.Output.Format {
+	case FormatJSONL:
+		record := map[string]any{}
+		for i, field := range rule.Fields {
+			record[field.Name] = values[i]
+		}
+
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode data: %w", err)
+		}
+
+		line = string(encoded)
+
+	default:
+		columns := make([]string, len(values))
+		for i, field := range rule.Fields {
+			columns[i] = field.format(values[i])
+		}
+
+		line = strings.Join(columns, rule.Output.Separator)
+	}
+
+	logFile := filepath.Join(p.datadir, rule.Output.File)
+	if err := processor.GetEffects(ctx).AppendFile(logFile, []byte(line+"\n")); err != nil {
+		return fmt.Errorf("failed to append data: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Match) matches(msg *email.Message) bool {
+	if m.subject != nil && !m.subject.MatchString(msg.GetSubject()) {
+		return false
+	}
+
+	if m.from != nil {
+		from := msg.GetFrom()
+		if from == nil || !m.from.MatchString(from.Address) {
+			return false
+		}
+	}
+
+	for header, re := range m.headers {
+		if !re.MatchString(msg.Header.Get(header)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Rule) extract(msg *email.Message) ([]any, error) {
+	values := make([]any, len(r.Fields))
+	sources := map[string]string{}
+
+	for i, field := range r.Fields {
+		key := string(field.Source) + ":" + field.Header
+
+		input, ok := sources[key]
+		if !ok {
+			var err error
+
+			input, err = field.input(msg)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", field.Name, err)
+			}
+
+			sources[key] = input
+		}
+
+		value, err := field.extract(input)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field.Name, err)
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func (f *Field) input(msg *email.Message) (string, error) {
+	switch f.Source {
+	case SourceSubject:
+		return msg.GetSubject(), nil
+
+	case SourceHeader:
+		return msg.Header.Get(f.Header), nil
+
+	case SourceDate:
+		return msg.Header.Get("Date"), nil
+
+	case SourceText, SourceHTML:
+		contentType := "text/plain"
+		if f.Source == SourceHTML {
+			contentType = "text/html"
+		}
+
+		parts, err := msg.Parts()
+		if err != nil {
+			return "", fmt.Errorf("failed to parse body: %w", err)
+		}
+
+		for _, part := range parts {
+			if part.ContentType == contentType {
+				return string(part.Body), nil
+			}
+		}
+
+		return "", fmt.Errorf("mail has no %s part", contentType)
+	}
+
+	return "", fmt.Errorf("unknown source %q", f.Source)
+}
+
+func (f *Field) extract(input string) (any, error) {
+	raw := input
+
+	if f.regex != nil {
+		matches := f.regex.FindStringSubmatch(input)
+		if matches == nil {
+			return nil, errors.New("regexp did not match")
+		}
+
+		raw = matches[1]
+	}
+
+	switch f.Type {
+	case TypeInt:
+		value, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", raw, err)
+		}
+
+		return value, nil
+
+	case TypeFloat:
+		value, err := toFloat(raw, f.Locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", raw, err)
+		}
+
+		return value, nil
+
+	case TypeDate:
+		return f.parseDate(raw)
+	}
+
+	return raw, nil
+}
+
+func (f *Field) parseDate(raw string) (time.Time, error) {
+	var (
+		parsed time.Time
+		err    error
+	)
+
+	if f.Layout == "" {
+		parsed, err = email.ParseDate(raw)
+	} else {
+		parsed, err = time.ParseInLocation(f.Layout, strings.TrimSpace(raw), time.UTC)
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %w", raw, err)
+	}
+
+	if f.nextWeekday != nil {
+		for parsed.Weekday() != *f.nextWeekday {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+	}
+
+	if f.TruncateToDay {
+		y, m, d := parsed.Date()
+		parsed = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	return parsed.Add(f.offset), nil
+}
+
+func (f *Field) format(value any) string {
+	if t, ok := value.(time.Time); ok {
+		value = t.Format(time.RFC3339)
+	}
+
+	if f.Format != "" {
+		return fmt.Sprintf(f.Format, value)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// toFloat turns a locale-formatted number into a float.
+func toFloat(val string, locale string) (float64, error) {
+	val = strings.TrimSpace(val)
+
+	switch locale {
+	case "de":
+		val = strings.ReplaceAll(val, ".", "")
+		val = strings.ReplaceAll(val, ",", ".")
+	case "en":
+		val = strings.ReplaceAll(val, ",", "")
+	}
+
+	return strconv.ParseFloat(val, 64)
+}
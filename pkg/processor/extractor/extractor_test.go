@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package extractor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// TestProcess ensures that the example rules produce the same data as the
+// hand-written rentablo and sunnyportal processors.
+func TestProcess(t *testing.T) {
+	rules, err := LoadRules("testdata")
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	testcases := []struct {
+		filename     string
+		dataFile     string
+		expectedLine string
+	}{
+		{
+			filename:     "../rentablo/testdata/a.eml",
+			dataFile:     "rentablo.csv",
+			expectedLine: "2022-01-30T12:00:00Z;0.05;-7.62;0.90;17.10\n",
+		},
+		{
+			filename:     "../rentablo/testdata/d.eml",
+			dataFile:     "rentablo.csv",
+			expectedLine: "2022-09-25T12:00:00Z;-2.71;-7.81;-9.10;-8.50\n",
+		},
+		{
+			filename:     "../sunnyportal/testdata/b.eml",
+			dataFile:     "sunnyportal.csv",
+			expectedLine: "2022-01-28T12:00:00Z;6.2050 kWh;0.4960 EUR;4.3440 kg\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.filename, func(t *testing.T) {
+			body, err := os.ReadFile(testcase.filename)
+			if err != nil {
+				t.Fatalf("Failed to read testdata: %v", err)
+			}
+
+			msg, err := email.ParseMessage(body)
+			if err != nil {
+				t.Fatalf("Failed to parse mail body: %v", err)
+			}
+
+			datadir := t.TempDir()
+			proc := New(datadir, rules)
+
+			consumed, _, err := proc.Process(context.Background(), logger, msg, nil)
+			if err != nil {
+				t.Fatalf("Failed to process mail: %v", err)
+			}
+
+			if !consumed {
+				t.Fatal("Expected mail to be consumed.")
+			}
+
+			data, err := os.ReadFile(filepath.Join(datadir, testcase.dataFile))
+			if err != nil {
+				t.Fatalf("Failed to read data file: %v", err)
+			}
+
+			if string(data) != testcase.expectedLine {
+				t.Fatalf("Expected %q, got %q.", testcase.expectedLine, string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package extractor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rule describes which e-mails to handle and what data to extract from them.
+type Rule struct {
+	Name   string  `json:"name"`
+	Match  Match   `json:"match"`
+	Fields []Field `json:"fields"`
+	Output Output  `json:"output"`
+	// Deliver controls whether the e-mail is still delivered after its data
+	// has been extracted. By default, e-mails are consumed.
+	Deliver bool `json:"deliver"`
+}
+
+// Match contains regular expressions that all need to match for a rule to
+// apply. Empty expressions are ignored.
+type Match struct {
+	Subject string            `json:"subject"`
+	From    string            `json:"from"`
+	Headers map[string]string `json:"headers"`
+
+	subject *regexp.Regexp
+	from    *regexp.Regexp
+	headers map[string]*regexp.Regexp
+}
+
+type Source string
+
+const (
+	SourceSubject Source = "subject"
+	SourceHeader  Source = "header"
+	SourceText    Source = "text"
+	SourceHTML    Source = "html"
+	// SourceDate is the message's Date header.
+	SourceDate Source = "date"
+)
+
+type FieldType string
+
+const (
+	TypeString FieldType = "string"
+	TypeInt    FieldType = "int"
+	TypeFloat  FieldType = "float"
+	TypeDate   FieldType = "date"
+)
+
+// Field is a single value to extract from an e-mail.
+type Field struct {
+	Name   string    `json:"name"`
+	Source Source    `json:"source"`
+	Header string    `json:"header"`
+	Regex  string    `json:"regex"`
+	Type   FieldType `json:"type"`
+
+	// Locale controls the decimal and thousands separators for floats,
+	// "de" (1.234,56) or "en" (1,234.56).
+	Locale string `json:"locale"`
+
+	// Layout is the Go time layout to parse dates with (not needed for
+	// the "date" source).
+	Layout string `json:"layout"`
+	// TruncateToDay removes the time of day (in the date's own timezone)
+	// and converts the date to UTC.
+	TruncateToDay bool `json:"truncateToDay"`
+	// NextWeekday moves dates forward until they fall on the given weekday.
+	NextWeekday string `json:"nextWeekday"`
+	// Offset is a Go duration that is added to dates after all other
+	// adjustments.
+	Offset string `json:"offset"`
+
+	// Format is the fmt format string for this field in CSV outputs. Dates
+	// are formatted as RFC3339 and then passed through Format.
+	Format string `json:"format"`
+
+	regex       *regexp.Regexp
+	nextWeekday *time.Weekday
+	offset      time.Duration
+}
+
+type OutputFormat string
+
+const (
+	FormatCSV   OutputFormat = "csv"
+	FormatJSONL OutputFormat = "jsonl"
+)
+
+type Output struct {
+	// File is relative to the datadir.
+	File      string       `json:"file"`
+	Format    OutputFormat `json:"format"`
+	Separator string       `json:"separator"`
+}
+
+// LoadRules loads all *.json files in the given directory. Each file can
+// contain a single rule or a list of rules.
+func LoadRules(directory string) ([]*Rule, error) {
+	files, err := filepath.Glob(filepath.Join(directory, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+
+	var rules []*Rule
+
+	for _, file := range files {
+		loaded, err := loadRuleFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule file %s: %w", filepath.Base(file), err)
+		}
+
+		rules = append(rules, loaded...)
+	}
+
+	return rules, nil
+}
+
+func loadRuleFile(filename string) ([]*Rule, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*Rule
+
+	if trimmed := strings.TrimSpace(string(content)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(content, &rules); err != nil {
+			return nil, err
+		}
+	} else {
+		rule := &Rule{}
+		if err := json.Unmarshal(content, rule); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	for _, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return errors.New("no name given")
+	}
+
+	if err := r.Match.compile(); err != nil {
+		return err
+	}
+
+	if len(r.Fields) == 0 {
+		return errors.New("no fields defined")
+	}
+
+	for i := range r.Fields {
+		if err := r.Fields[i].compile(); err != nil {
+			return fmt.Errorf("field %q: %w", r.Fields[i].Name, err)
+		}
+	}
+
+	if r.Output.File == "" {
+		return errors.New("no output file given")
+	}
+
+	if filepath.IsAbs(r.Output.File) || strings.Contains(r.Output.File, "..") {
+		return errors.New("output file must be relative to the datadir")
+	}
+
+	switch r.Output.Format {
+	case "":
+		r.Output.Format = FormatCSV
+	case FormatCSV, FormatJSONL:
+	default:
+		return fmt.Errorf("unknown output format %q", r.Output.Format)
+	}
+
+	if r.Output.Separator == "" {
+		r.Output.Separator = ";"
+	}
+
+	return nil
+}
+
+func (m *Match) compile() error {
+	var err error
+
+	if m.Subject == "" && m.From == "" && len(m.Headers) == 0 {
+		return errors.New("no match conditions given")
+	}
+
+	if m.Subject != "" {
+		if m.subject, err = regexp.Compile(m.Subject); err != nil {
+			return fmt.Errorf("invalid subject expression: %w", err)
+		}
+	}
+
+	if m.From != "" {
+		if m.from, err = regexp.Compile(m.From); err != nil {
+			return fmt.Errorf("invalid from expression: %w", err)
+		}
+	}
+
+	m.headers = map[string]*regexp.Regexp{}
+	for header, expr := range m.Headers {
+		if m.headers[header], err = regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid expression for header %s: %w", header, err)
+		}
+	}
+
+	return nil
+}
+
+func (f *Field) compile() error {
+	if f.Name == "" {
+		return errors.New("no name given")
+	}
+
+	switch f.Source {
+	case SourceSubject, SourceText, SourceHTML, SourceDate:
+	case SourceHeader:
+		if f.Header == "" {
+			return errors.New("no header name given")
+		}
+	default:
+		return fmt.Errorf("unknown source %q", f.Source)
+	}
+
+	if f.Regex == "" {
+		if f.Source != SourceDate {
+			return errors.New("no expression given")
+		}
+	} else {
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+
+		if re.NumSubexp() < 1 {
+			return errors.New("expression must contain a capture group")
+		}
+
+		f.regex = re
+	}
+
+	switch f.Type {
+	case "":
+		f.Type = TypeString
+	case TypeString, TypeInt:
+	case TypeFloat:
+		if f.Locale != "" && f.Locale != "de" && f.Locale != "en" {
+			return fmt.Errorf("unknown locale %q", f.Locale)
+		}
+	case TypeDate:
+		if f.Layout == "" && f.Source != SourceDate {
+			return errors.New("no date layout given")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", f.Type)
+	}
+
+	if f.NextWeekday != "" {
+		weekday, err := parseWeekday(f.NextWeekday)
+		if err != nil {
+			return err
+		}
+
+		f.nextWeekday = &weekday
+	}
+
+	if f.Offset != "" {
+		offset, err := time.ParseDuration(f.Offset)
+		if err != nil {
+			return fmt.Errorf("invalid offset: %w", err)
+		}
+
+		f.offset = offset
+	}
+
+	return nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), s) {
+			return d, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("invalid weekday %q", s)
+}
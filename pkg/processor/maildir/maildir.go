@@ -5,19 +5,37 @@ package maildir
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
 	"go.xrstf.de/rudi-lda/pkg/maildir"
 	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+	"go.xrstf.de/rudi-lda/pkg/quarantine"
 	"go.xrstf.de/rudi-lda/pkg/rudilib"
+	"go.xrstf.de/rudi-lda/pkg/screener"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+	"go.xrstf.de/rudi-lda/pkg/thread"
 )
 
 type Proc struct {
 	mailDirectory string
 	folderScript  string
+	threads       *thread.Index
+	followThreads bool
+	mutedFolder   string
+	archiveFolder string
+	spamPolicy    spam.Policy
+	junkFolder    string
+
+	screener       *screener.Screener
+	screenerFolder string
+	quarantine     *quarantine.Quarantine
+	user           string
 }
 
 func New(mailDirectory string, folderScript string) *Proc {
@@ -27,6 +45,54 @@ func New(mailDirectory string, folderScript string) *Proc {
 	}
 }
 
+// WithThreads enables the thread index. The folder of the closest known
+// ancestor is made available to the folder script as `.thread`. If follow
+// is true, replies are delivered into the same folder as their parent unless
+// the folder script decides otherwise.
+func (p *Proc) WithThreads(index *thread.Index, follow bool) *Proc {
+	p.threads = index
+	p.followThreads = follow
+
+	return p
+}
+
+// WithMuting enables muting threads (requires the thread index). Messages
+// moved into the mutedFolder mute their thread and are then moved into the
+// archiveFolder. Later messages of muted threads are delivered as already
+// read into the archiveFolder.
+func (p *Proc) WithMuting(mutedFolder string, archiveFolder string) *Proc {
+	p.mutedFolder = mutedFolder
+	p.archiveFolder = archiveFolder
+
+	return p
+}
+
+// WithSpamPolicy makes the verdict of the antispam processor available to
+// the folder script as `.spam` and handles the junk and keyword actions of
+// the policy. E-mails are delivered into the junkFolder unless the folder
+// script decides otherwise.
+func (p *Proc) WithSpamPolicy(policy spam.Policy, junkFolder string) *Proc {
+	p.spamPolicy = policy
+	p.junkFolder = junkFolder
+
+	return p
+}
+
+// WithScreener enables screening senders. E-mails from senders the user
+// has not decided about yet are delivered into the screenerFolder, unless
+// the folder script decides otherwise. Senders of e-mails moved into its
+// Accept subfolder are accepted and their e-mails are moved into the inbox,
+// senders of e-mails moved into its Reject subfolder are rejected and their
+// e-mails (including all later ones) are put into the quarantine.
+func (p *Proc) WithScreener(s *screener.Screener, screenerFolder string, q *quarantine.Quarantine, user string) *Proc {
+	p.screener = s
+	p.screenerFolder = screenerFolder
+	p.quarantine = q
+	p.user = user
+
+	return p
+}
+
 func (*Proc) Name() string {
 	return "maildir"
 }
@@ -37,38 +103,288 @@ func (p *Proc) Process(ctx context.Context, logger logrus.FieldLogger, msg *emai
 		return false, nil, fmt.Errorf("invalid maildir %q: %w", p.mailDirectory, err)
 	}
 
-	folder, err := p.determineFolder(ctx, msg)
+	effects := processor.GetEffects(ctx)
+
+	p.processMutedFolder(logger, effects, md)
+	p.processScreenerFolders(logger, effects, md)
+
+	status, err := p.senderStatus(msg)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to screen sender.")
+	}
+
+	if status == screener.Rejected {
+		processor.Notef(ctx, "sender %s has been rejected", screener.Sender(msg))
+
+		if err := p.quarantineRejected(ctx, msg); err == nil {
+			logger.Info("Quarantined e-mail from rejected sender.")
+			processor.SetDestination(ctx, "(discarded)")
+
+			return true, nil, nil
+		}
+
+		// deliver as usual to prevent data loss
+		logger.WithError(err).Error("Failed to quarantine e-mail from rejected sender.")
+	}
+
+	thr := p.resolveThread(logger, msg)
+
+	if thr != nil && thr.Muted && p.mutedFolder != "" {
+		logger = logger.WithField("folder", p.archiveFolder).WithField("root", thr.Root)
+		logger.Info("Delivering muted thread.")
+
+		msg.SetHeader("X-Rudi-LDA-Muted", thr.Root)
+
+		if err := p.deliver(ctx, md, p.archiveFolder, msg, "S"); err != nil {
+			return false, nil, fmt.Errorf("failed to deliver into maildir: %w", err)
+		}
+
+		p.recordThread(logger, effects, msg, thr, p.archiveFolder)
+
+		return true, nil, nil
+	}
+
+	folder, err := p.DetermineFolder(ctx, msg, thr)
 	if err != nil {
-		logger.WithError(err).Error("Failed to determine folder.")
+		if errors.Is(err, rudilib.ErrLimitExceeded) {
+			logger.WithError(err).Error("Folder script was aborted, delivering into inbox.")
+		} else {
+			logger.WithError(err).Error("Failed to determine folder.")
+		}
 		// continue, i.e. deliver into root maildir folder (inbox)
 	}
 
+	if thr != nil && thr.Parent != nil {
+		logger = logger.WithField("parent", thr.ParentID)
+	}
+
+	verdict := p.spamVerdict(msg)
+
+	var flags string
+	if verdict != nil && p.spamPolicy.Has(verdict.Status, spam.ActionKeyword) {
+		err = effects.Apply(fmt.Sprintf("register keyword %s", spam.JunkKeyword), func() (err error) {
+			flags, err = md.KeywordFlag(folder, spam.JunkKeyword)
+			return err
+		})
+		if err != nil {
+			logger.WithError(err).Warn("Failed to set junk keyword.")
+		}
+	}
+
 	logger.WithField("folder", folder).Info("Delivering.")
 
-	if err := md.Deliver(folder, msg); err != nil {
+	if err := p.deliver(ctx, md, folder, msg, flags); err != nil {
 		return false, nil, fmt.Errorf("failed to deliver into maildir: %w", err)
 	}
 
+	p.recordThread(logger, effects, msg, thr, folder)
+
 	return true, nil, nil
 }
 
-func (p *Proc) determineFolder(ctx context.Context, msg *email.Message) (string, error) {
+func (p *Proc) deliver(ctx context.Context, md *maildir.Maildir, folder string, msg *email.Message, flags string) error {
+	name := folder
+	if name == "" {
+		name = "INBOX"
+	}
+
+	processor.SetDestination(ctx, name)
+
+	return processor.GetEffects(ctx).Apply(fmt.Sprintf("deliver into %s", name), func() error {
+		return md.DeliverWithFlags(folder, msg, flags)
+	})
+}
+
+// DetermineFolder decides where a message is delivered to (thr can be nil).
+// The folder script's decision takes precedence, followed by the junk folder
+// for spam, the screener folder for unknown senders and the folder of the
+// message's thread.
+func (p *Proc) DetermineFolder(ctx context.Context, msg *email.Message, thr *thread.Thread) (string, error) {
+	verdict := p.spamVerdict(msg)
+
+	// errors have already been logged by Process, unknown senders are
+	// better delivered as usual than being hidden in the screener
+	status, _ := p.senderStatus(msg)
+
+	extraData := threadData(thr)
+	extraData["spam"] = spamData(verdict)
+	extraData["screener"] = screenerData(status)
+
+	folder, decided, err := p.determineFolder(ctx, msg, extraData)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case decided:
+		// keep the folder script's decision
+	case verdict != nil && p.spamPolicy.Has(verdict.Status, spam.ActionJunk):
+		folder = p.junkFolder
+	case status == screener.Unknown && (thr == nil || thr.Parent == nil):
+		// replies to known conversations do not need to be screened
+		folder = p.screenerFolder
+	case p.followThreads && thr != nil && thr.Parent != nil:
+		folder = thr.Parent.Folder
+	}
+
+	return folder, nil
+}
+
+// processMutedFolder mutes the threads of all messages the user has moved
+// into the muted folder and then moves them into the archive.
+func (p *Proc) processMutedFolder(logger logrus.FieldLogger, effects processor.Effects, md *maildir.Maildir) {
+	if p.threads == nil || p.mutedFolder == "" {
+		return
+	}
+
+	files, err := md.List(p.mutedFolder)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to list muted folder.")
+		return
+	}
+
+	for _, file := range files {
+		mutedMsg, err := md.Read(file)
+		if err != nil {
+			logger.WithError(err).WithField("file", file).Warn("Failed to read muted message.")
+			continue
+		}
+
+		thr, err := p.threads.Resolve(mutedMsg)
+		if err != nil {
+			logger.WithError(err).WithField("file", file).Warn("Failed to resolve muted thread.")
+			continue
+		}
+
+		err = effects.Apply(fmt.Sprintf("mute thread %s", thr.Root), func() error {
+			root, err := p.threads.Mute(thr.Root)
+			if err != nil {
+				return err
+			}
+
+			logger.WithField("root", root).Info("Muted thread.")
+
+			return nil
+		})
+		if err != nil {
+			logger.WithError(err).WithField("file", file).Warn("Failed to mute thread.")
+			continue
+		}
+
+		err = effects.Apply(fmt.Sprintf("move %s into %s", file, p.archiveFolder), func() error {
+			return md.Move(file, p.archiveFolder)
+		})
+		if err != nil {
+			logger.WithError(err).WithField("file", file).Warn("Failed to archive muted message.")
+		}
+	}
+}
+
+func (p *Proc) resolveThread(logger logrus.FieldLogger, msg *email.Message) *thread.Thread {
+	if p.threads == nil {
+		return nil
+	}
+
+	thr, err := p.threads.Resolve(msg)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to resolve thread.")
+		return nil
+	}
+
+	return thr
+}
+
+func (p *Proc) recordThread(logger logrus.FieldLogger, effects processor.Effects, msg *email.Message, thr *thread.Thread, folder string) {
+	if p.threads == nil {
+		return
+	}
+
+	entry := thread.Entry{
+		Folder:    folder,
+		Delivered: time.Now(),
+	}
+
+	if thr != nil {
+		entry.Root = thr.Root
+	}
+
+	err := effects.Apply("record e-mail in thread index", func() error {
+		return p.threads.Record(msg.GetMessageID(), entry)
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to record message in thread index.")
+	}
+}
+
+func threadData(thr *thread.Thread) map[string]any {
+	info := map[string]any{
+		"known":  false,
+		"folder": "",
+		"parent": "",
+		"root":   "",
+	}
+
+	if thr != nil {
+		info["root"] = thr.Root
+
+		if thr.Parent != nil {
+			info["known"] = true
+			info["folder"] = thr.Parent.Folder
+			info["parent"] = thr.ParentID
+		}
+	}
+
+	return map[string]any{
+		"thread": info,
+	}
+}
+
+// spamVerdict returns the result of the antispam processor, if any.
+func (p *Proc) spamVerdict(msg *email.Message) *spam.Result {
+	if p.spamPolicy == nil {
+		return nil
+	}
+
+	return spam.ParseVerdict(msg.Header.Get(spam.VerdictHeader))
+}
+
+func spamData(verdict *spam.Result) map[string]any {
+	info := map[string]any{
+		"status": "",
+		"rule":   "",
+		"score":  0.0,
+	}
+
+	if verdict != nil {
+		info["status"] = string(verdict.Status)
+		info["rule"] = verdict.Rule
+		info["score"] = verdict.Score
+	}
+
+	return info
+}
+
+// determineFolder runs the folder script. The returned bool is false if the
+// script did not make a decision (no script or a null result).
+func (p *Proc) determineFolder(ctx context.Context, msg *email.Message, extraData map[string]any) (string, bool, error) {
 	if p.folderScript == "" {
-		return "", nil
+		return "", false, nil
 	}
 
-	result, err := rudilib.ProcessMessage(ctx, p.folderScript, msg, nil, nil)
+	result, err := rudilib.ProcessMessage(ctx, p.folderScript, msg, extraData, nil, nil)
 	if err != nil {
-		return "", fmt.Errorf("script failed: %w", err)
+		return "", false, fmt.Errorf("script failed: %w", err)
 	}
 
+	processor.Notef(ctx, "folder script returned %#v", result)
+
 	if result == nil {
-		return "", nil
+		return "", false, nil
 	}
 
 	if s, ok := result.(string); ok {
-		return s, nil
+		return s, true, nil
 	}
 
-	return "", fmt.Errorf("script did not return string, but %T", result)
+	return "", false, fmt.Errorf("script did not return string, but %T", result)
 }
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+	"go.xrstf.de/rudi-lda/pkg/screener"
+)
+
+// senderStatus returns the screener's decision about the sender of the
+// message, or an empty status if screening is disabled.
+func (p *Proc) senderStatus(msg *email.Message) (screener.Status, error) {
+	if p.screener == nil {
+		return "", nil
+	}
+
+	return p.screen(screener.Sender(msg))
+}
+
+// screen returns the decision about the sender. E-mails without a sender
+// (like bounces) cannot be decided about and, just like senders that cannot
+// be screened because of errors, are accepted.
+func (p *Proc) screen(sender string) (screener.Status, error) {
+	status, err := p.screener.Status(sender)
+	if err != nil {
+		if errors.Is(err, screener.ErrNoSender) {
+			return screener.Accepted, nil
+		}
+
+		return screener.Accepted, err
+	}
+
+	return status, nil
+}
+
+func screenerData(status screener.Status) map[string]any {
+	return map[string]any{
+		"status": string(status),
+	}
+}
+
+func (p *Proc) quarantineRejected(ctx context.Context, msg *email.Message) error {
+	if p.quarantine == nil {
+		return errors.New("no quarantine configured")
+	}
+
+	return processor.GetEffects(ctx).Apply("quarantine e-mail", func() error {
+		_, err := p.quarantine.Add(msg, p.user, screener.Rule, 0)
+		return err
+	})
+}
+
+// processScreenerFolders records the decisions the user has made by moving
+// e-mails into the Accept and Reject subfolders of the screener folder.
+// Afterwards, all e-mails of decided senders are moved out of the screener
+// folder (and its subfolders): accepted ones into the inbox, rejected ones
+// into the quarantine. As this requires reading every e-mail in the screener
+// folder, it is only done when decisions have changed since the last time.
+func (p *Proc) processScreenerFolders(logger logrus.FieldLogger, effects processor.Effects, md *maildir.Maildir) {
+	if p.screener == nil {
+		return
+	}
+
+	decided := false
+
+	decisions := []struct {
+		folder string
+		status screener.Status
+	}{
+		{folder: screener.AcceptFolder(p.screenerFolder), status: screener.Accepted},
+		{folder: screener.RejectFolder(p.screenerFolder), status: screener.Rejected},
+	}
+
+	for _, decision := range decisions {
+		files, err := md.List(decision.folder)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to list screener folder.")
+			continue
+		}
+
+		for _, file := range files {
+			screenedMsg, err := md.Read(file)
+			if err != nil {
+				logger.WithError(err).WithField("file", file).Warn("Failed to read screened message.")
+				continue
+			}
+
+			decided = true
+
+			sender := screener.Sender(screenedMsg)
+			if sender != "" {
+				err = effects.Apply(fmt.Sprintf("%s sender %s", decision.status, sender), func() error {
+					var err error
+
+					if decision.status == screener.Accepted {
+						_, err = p.screener.Accept([]string{sender})
+					} else {
+						_, err = p.screener.Reject([]string{sender})
+					}
+
+					if err == nil {
+						logger.WithField("sender", sender).Infof("Screener %s sender.", decision.status)
+					}
+
+					return err
+				})
+				if err != nil {
+					logger.WithError(err).WithField("file", file).Warn("Failed to record screener decision.")
+					continue
+				}
+			}
+
+			p.releaseScreened(logger, effects, md, file, screenedMsg, decision.status)
+		}
+	}
+
+	// during dry-runs, decisions are not recorded and do not change the lists
+	if !decided {
+		changed, err := p.screener.Changed()
+		if err != nil {
+			logger.WithError(err).Warn("Failed to check for screener decisions.")
+		} else if !changed {
+			return
+		}
+	}
+
+	scanned := time.Now()
+
+	files, err := md.List(p.screenerFolder)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to list screener folder.")
+		return
+	}
+
+	for _, file := range files {
+		screenedMsg, err := md.Read(file)
+		if err != nil {
+			logger.WithError(err).WithField("file", file).Warn("Failed to read screened message.")
+			continue
+		}
+
+		status, err := p.screen(screener.Sender(screenedMsg))
+		if err != nil {
+			logger.WithError(err).WithField("file", file).Warn("Failed to screen sender.")
+			continue
+		}
+
+		if status != screener.Unknown {
+			p.releaseScreened(logger, effects, md, file, screenedMsg, status)
+		}
+	}
+
+	err = effects.Apply("remember scanning the screener folder", func() error {
+		return p.screener.Scanned(scanned)
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to remember scanning the screener folder.")
+	}
+}
+
+// releaseScreened moves a screened e-mail into the inbox or the quarantine.
+func (p *Proc) releaseScreened(logger logrus.FieldLogger, effects processor.Effects, md *maildir.Maildir, file string, msg *email.Message, status screener.Status) {
+	var err error
+
+	if status == screener.Accepted {
+		err = effects.Apply(fmt.Sprintf("move %s into INBOX", file), func() error {
+			return md.Move(file, "")
+		})
+	} else {
+		err = effects.Apply(fmt.Sprintf("quarantine %s", file), func() error {
+			if p.quarantine == nil {
+				return errors.New("no quarantine configured")
+			}
+
+			if _, err := p.quarantine.Add(msg, p.user, screener.Rule, 0); err != nil {
+				return err
+			}
+
+			return os.Remove(file)
+		})
+	}
+
+	if err != nil {
+		logger.WithError(err).WithField("file", file).Warn("Failed to move screened message.")
+	}
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/quarantine"
+	"go.xrstf.de/rudi-lda/pkg/screener"
+	"go.xrstf.de/rudi-lda/pkg/test"
+	"go.xrstf.de/rudi-lda/pkg/thread"
+)
+
+type screenerSetup struct {
+	md         *maildir.Maildir
+	screener   *screener.Screener
+	quarantine *quarantine.Quarantine
+	threads    *thread.Index
+	proc       *Proc
+}
+
+func newScreenerSetup(t *testing.T) *screenerSetup {
+	t.Helper()
+
+	base := t.TempDir()
+	datadir := t.TempDir()
+
+	md, err := maildir.New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	s := screener.New(datadir)
+	q := quarantine.New(quarantine.Directory(datadir), time.Hour)
+	threads := thread.New(filepath.Join(datadir, "threads.json"), time.Hour)
+
+	return &screenerSetup{
+		md:         md,
+		screener:   s,
+		quarantine: q,
+		threads:    threads,
+		proc:       New(base, "").WithThreads(threads, false).WithScreener(s, screener.DefaultFolder, q, "me"),
+	}
+}
+
+func (s *screenerSetup) process(t *testing.T, msg *email.Message) bool {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	consumed, _, err := s.proc.Process(context.Background(), logger, msg, &metrics.Metrics{})
+	if err != nil {
+		t.Fatalf("Failed to process e-mail: %v", err)
+	}
+
+	return consumed
+}
+
+// subjects returns the sorted subjects of all e-mails in the folder.
+func (s *screenerSetup) subjects(t *testing.T, folder string) []string {
+	t.Helper()
+
+	files, err := s.md.List(folder)
+	if err != nil {
+		t.Fatalf("Failed to list folder: %v", err)
+	}
+
+	subjects := []string{}
+	for _, file := range files {
+		msg, err := s.md.Read(file)
+		if err != nil {
+			t.Fatalf("Failed to read e-mail: %v", err)
+		}
+
+		subjects = append(subjects, msg.GetSubject())
+	}
+
+	sort.Strings(subjects)
+
+	return subjects
+}
+
+func (s *screenerSetup) quarantined(t *testing.T) []string {
+	t.Helper()
+
+	entries, err := s.quarantine.List("me")
+	if err != nil {
+		t.Fatalf("Failed to list quarantine: %v", err)
+	}
+
+	subjects := []string{}
+	for _, entry := range entries {
+		if entry.Rule != screener.Rule {
+			t.Errorf("Expected e-mail to be quarantined because of %q, got %q.", screener.Rule, entry.Rule)
+		}
+
+		subjects = append(subjects, entry.Subject)
+	}
+
+	sort.Strings(subjects)
+
+	return subjects
+}
+
+func assertSubjects(t *testing.T, kind string, expected []string, actual []string) {
+	t.Helper()
+
+	if len(expected) != len(actual) {
+		t.Fatalf("Expected %s to contain %v, got %v.", kind, expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("Expected %s to contain %v, got %v.", kind, expected, actual)
+		}
+	}
+}
+
+func newScreenerMessage(from string, subject string) *email.Message {
+	return test.NewMessageBuilder().
+		WithFrom(from).
+		WithSubject(subject).
+		WithRawHeader("Message-ID", "<"+subject+"@example.com>").
+		Build()
+}
+
+func TestScreenerRouting(t *testing.T) {
+	setup := newScreenerSetup(t)
+
+	if _, err := setup.screener.Accept([]string{"friend@example.com"}); err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	if err := setup.threads.Record("<conversation@example.com>", thread.Entry{Delivered: time.Now()}); err != nil {
+		t.Fatalf("Failed to record thread: %v", err)
+	}
+
+	reply := newScreenerMessage("stranger@example.org", "reply")
+	reply.Header["In-Reply-To"] = []string{"<conversation@example.com>"}
+
+	messages := []*email.Message{
+		newScreenerMessage("stranger@example.org", "unknown"),
+		newScreenerMessage("Friend@example.com", "accepted"),
+		reply,
+		test.NewMessageBuilder().WithSubject("bounce").Build(),
+	}
+
+	for _, msg := range messages {
+		if !setup.process(t, msg) {
+			t.Fatalf("Expected %q to be consumed.", msg.GetSubject())
+		}
+	}
+
+	assertSubjects(t, "the inbox", []string{"accepted", "bounce", "reply"}, setup.subjects(t, ""))
+	assertSubjects(t, "the screener folder", []string{"unknown"}, setup.subjects(t, screener.DefaultFolder))
+}
+
+func TestScreenerRejected(t *testing.T) {
+	setup := newScreenerSetup(t)
+
+	if _, err := setup.screener.Reject([]string{"example.net"}); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+
+	if !setup.process(t, newScreenerMessage("spammer@example.net", "rejected")) {
+		t.Fatal("Expected e-mail from rejected sender to be consumed.")
+	}
+
+	assertSubjects(t, "the inbox", []string{}, setup.subjects(t, ""))
+	assertSubjects(t, "the screener folder", []string{}, setup.subjects(t, screener.DefaultFolder))
+	assertSubjects(t, "the quarantine", []string{"rejected"}, setup.quarantined(t))
+}
+
+func TestScreenerFolders(t *testing.T) {
+	setup := newScreenerSetup(t)
+
+	screened := map[string]*email.Message{
+		screener.AcceptFolder(screener.DefaultFolder): newScreenerMessage("friend@example.com", "moved-to-accept"),
+		screener.RejectFolder(screener.DefaultFolder): newScreenerMessage("spammer@example.net", "moved-to-reject"),
+	}
+
+	for folder, msg := range screened {
+		if err := setup.md.Deliver(folder, msg); err != nil {
+			t.Fatalf("Failed to deliver: %v", err)
+		}
+	}
+
+	for _, msg := range []*email.Message{
+		newScreenerMessage("friend@example.com", "friend-waiting"),
+		newScreenerMessage("spammer@example.net", "spammer-waiting"),
+		newScreenerMessage("stranger@example.org", "stranger-waiting"),
+	} {
+		if err := setup.md.Deliver(screener.DefaultFolder, msg); err != nil {
+			t.Fatalf("Failed to deliver: %v", err)
+		}
+	}
+
+	setup.process(t, newScreenerMessage("friend@example.com", "new"))
+
+	for address, expected := range map[string]screener.Status{
+		"friend@example.com":   screener.Accepted,
+		"spammer@example.net":  screener.Rejected,
+		"stranger@example.org": screener.Unknown,
+	} {
+		status, err := setup.screener.Status(address)
+		if err != nil {
+			t.Fatalf("Failed to screen: %v", err)
+		}
+
+		if status != expected {
+			t.Errorf("Expected %s to be %s, got %s.", address, expected, status)
+		}
+	}
+
+	assertSubjects(t, "the inbox", []string{"friend-waiting", "moved-to-accept", "new"}, setup.subjects(t, ""))
+	assertSubjects(t, "the screener folder", []string{"stranger-waiting"}, setup.subjects(t, screener.DefaultFolder))
+	assertSubjects(t, "the accept folder", []string{}, setup.subjects(t, screener.AcceptFolder(screener.DefaultFolder)))
+	assertSubjects(t, "the reject folder", []string{}, setup.subjects(t, screener.RejectFolder(screener.DefaultFolder)))
+	assertSubjects(t, "the quarantine", []string{"moved-to-reject", "spammer-waiting"}, setup.quarantined(t))
+}
+
+func TestScreenerFolderOnlyScannedOnChanges(t *testing.T) {
+	setup := newScreenerSetup(t)
+
+	if _, err := setup.screener.Accept([]string{"friend@example.com"}); err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	setup.process(t, newScreenerMessage("friend@example.com", "first"))
+
+	// e-mails in the screener folder are left alone as long as no
+	// decisions have been made, even if their sender is accepted
+	if err := setup.md.Deliver(screener.DefaultFolder, newScreenerMessage("friend@example.com", "waiting")); err != nil {
+		t.Fatalf("Failed to deliver: %v", err)
+	}
+
+	setup.process(t, newScreenerMessage("friend@example.com", "second"))
+
+	assertSubjects(t, "the screener folder", []string{"waiting"}, setup.subjects(t, screener.DefaultFolder))
+
+	if _, err := setup.screener.Accept([]string{"other@example.com"}); err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	setup.process(t, newScreenerMessage("friend@example.com", "third"))
+
+	assertSubjects(t, "the screener folder", []string{}, setup.subjects(t, screener.DefaultFolder))
+	assertSubjects(t, "the inbox", []string{"first", "second", "third", "waiting"}, setup.subjects(t, ""))
+}
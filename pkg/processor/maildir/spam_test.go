@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package maildir
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor/antispam"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+	"go.xrstf.de/rudi-lda/pkg/test"
+)
+
+func TestSpamVerdictIsDelivered(t *testing.T) {
+	testcases := []struct {
+		name     string
+		script   string
+		header   string
+		expected string
+		status   spam.Status
+	}{
+		{
+			name:     "spam goes into the junk folder",
+			script:   `(score! 12 "foo")`,
+			expected: "Junk",
+			status:   spam.Spam,
+		},
+		{
+			name:     "forged verdicts are ignored",
+			script:   `(score! 1 "foo")`,
+			header:   "X-Rudi-LDA-Antispam: status:spam,score:99.0,rule:forged\r\n",
+			expected: "",
+			status:   spam.Ham,
+		},
+	}
+
+	policy, err := spam.NewPolicy([]string{"junk"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			scriptFile, err := test.TempScript(testcase.script)
+			if err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+			defer os.Remove(scriptFile)
+
+			msg, err := email.ParseMessage([]byte(testcase.header + "Subject: Hello\r\n\r\nBody.\r\n"))
+			if err != nil {
+				t.Fatalf("Failed to parse message: %v", err)
+			}
+
+			base := t.TempDir()
+
+			antispamProc := antispam.New(scriptFile).WithPolicy(policy, antispam.DefaultTag)
+			maildirProc := New(base, "").WithSpamPolicy(policy, "Junk")
+
+			ctx := context.Background()
+
+			consumed, updated, err := antispamProc.Process(ctx, logger, msg, &metrics.Metrics{})
+			if err != nil {
+				t.Fatalf("Failed to run antispam processor: %v", err)
+			}
+			if consumed {
+				t.Fatal("Expected e-mail not to be consumed by the antispam processor.")
+			}
+
+			if _, _, err := maildirProc.Process(ctx, logger, updated, &metrics.Metrics{}); err != nil {
+				t.Fatalf("Failed to run maildir processor: %v", err)
+			}
+
+			md, err := maildir.New(base)
+			if err != nil {
+				t.Fatalf("Failed to open Maildir: %v", err)
+			}
+
+			files, err := md.List(testcase.expected)
+			if err != nil {
+				t.Fatalf("Failed to list folder: %v", err)
+			}
+
+			if len(files) != 1 {
+				t.Fatalf("Expected e-mail to be delivered into %q, but found %d e-mails there.", testcase.expected, len(files))
+			}
+
+			delivered, err := md.Read(files[0])
+			if err != nil {
+				t.Fatalf("Failed to read e-mail: %v", err)
+			}
+
+			verdict := spam.ParseVerdict(delivered.Header.Get(spam.VerdictHeader))
+			if verdict == nil || verdict.Status != testcase.status {
+				t.Errorf("Expected %s verdict, got %+v.", testcase.status, verdict)
+			}
+		})
+	}
+}
@@ -15,8 +15,20 @@ import (
 )
 
 func Pipeline(ctx context.Context, logger logrus.FieldLogger, processors []Processor, msg *email.Message, metricsData *metrics.Metrics) (*email.Message, error) {
+	trace := GetTrace(ctx)
+
 	for _, processor := range processors {
+		trace.begin(processor.Name(), msg.Header)
+
 		consumed, newMsg, err := tryProcessor(ctx, logger, processor, msg, metricsData)
+
+		if newMsg != nil {
+			trace.end(consumed, err, newMsg.Header)
+		} else {
+			// consumed messages can be modified in-place, too
+			trace.end(consumed, err, msg.Header)
+		}
+
 		if err != nil {
 			// remember this error forever
 			if newMsg == nil {
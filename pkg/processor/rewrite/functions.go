@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rewrite
+
+import (
+	"net/textproto"
+	"path"
+	"strings"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// mutator provides Rudi functions that modify a single message.
+type mutator struct {
+	msg     *email.Message
+	changes []string
+}
+
+func newMutator(msg *email.Message) *mutator {
+	return &mutator{
+		msg: msg,
+	}
+}
+
+func (m *mutator) functions() rudi.Functions {
+	return rudi.Functions{
+		"set-header!":     rudi.NewFunctionBuilder(m.setHeaderFunc).WithDescription("replaces all values of a header with the given value (non-ASCII values are encoded)").Build(),
+		"add-header!":     rudi.NewFunctionBuilder(m.addHeaderFunc).WithDescription("adds another value to a header (non-ASCII values are encoded)").Build(),
+		"remove-header!":  rudi.NewFunctionBuilder(m.removeHeaderFunc).WithDescription("removes all values of a header, returns true if the header was set").Build(),
+		"prefix-subject!": rudi.NewFunctionBuilder(m.prefixSubjectFunc).WithDescription("prefixes the subject with the given string, unless it is already prefixed").Build(),
+		"strip-part!":     rudi.NewFunctionBuilder(m.stripPartFunc, m.stripPartWithSizeFunc).WithDescription("removes all MIME parts whose content type matches the given pattern (e.g. \"image/*\") and that are at least the given number of bytes large, returns the number of removed parts").Build(),
+	}
+}
+
+func (m *mutator) setHeaderFunc(name string, value string) (any, error) {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+
+	m.msg.SetHeader(name, value)
+	m.changes = append(m.changes, "set "+name)
+
+	return value, nil
+}
+
+func (m *mutator) addHeaderFunc(name string, value string) (any, error) {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+
+	m.msg.AddHeader(name, value)
+	m.changes = append(m.changes, "add "+name)
+
+	return value, nil
+}
+
+func (m *mutator) removeHeaderFunc(name string) (any, error) {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+
+	if _, exists := m.msg.Header[name]; !exists {
+		return false, nil
+	}
+
+	delete(m.msg.Header, name)
+	m.changes = append(m.changes, "remove "+name)
+
+	return true, nil
+}
+
+func (m *mutator) prefixSubjectFunc(prefix string) (any, error) {
+	subject := m.msg.GetSubject()
+
+	if !strings.HasPrefix(subject, prefix) {
+		subject = prefix + subject
+
+		m.msg.SetSubject(subject)
+		m.changes = append(m.changes, "prefix Subject")
+	}
+
+	return subject, nil
+}
+
+func (m *mutator) stripPartFunc(contentType string) (any, error) {
+	return m.stripPartWithSizeFunc(contentType, 0)
+}
+
+func (m *mutator) stripPartWithSizeFunc(contentType string, minSize int64) (any, error) {
+	pattern := strings.ToLower(contentType)
+
+	stripped, err := m.msg.StripParts(func(p *email.Part) bool {
+		if int64(len(p.Body)) < minSize {
+			return false
+		}
+
+		matched, _ := path.Match(pattern, strings.ToLower(p.ContentType))
+		return matched
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stripped > 0 {
+		m.changes = append(m.changes, "strip "+contentType)
+	}
+
+	return int64(stripped), nil
+}
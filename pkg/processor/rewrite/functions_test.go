@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rewrite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+const headerMessage = "Subject: Hello\r\n" +
+	"X-Foo: one\r\n" +
+	"X-Foo: two\r\n" +
+	"\r\n" +
+	"Body.\r\n"
+
+func TestHeaderFunctions(t *testing.T) {
+	testcases := []struct {
+		name            string
+		call            func(m *mutator) (any, error)
+		header          string
+		expected        []string
+		expectedResult  any
+		expectedChanges []string
+	}{
+		{
+			name:            "set-header! replaces all values",
+			call:            func(m *mutator) (any, error) { return m.setHeaderFunc("x-foo", "three") },
+			header:          "X-Foo",
+			expected:        []string{"three"},
+			expectedResult:  "three",
+			expectedChanges: []string{"set X-Foo"},
+		},
+		{
+			name:            "set-header! encodes non-ASCII values",
+			call:            func(m *mutator) (any, error) { return m.setHeaderFunc("X-Greeting", "Grüße") },
+			header:          "X-Greeting",
+			expected:        []string{"=?utf-8?q?Gr=C3=BC=C3=9Fe?="},
+			expectedResult:  "Grüße",
+			expectedChanges: []string{"set X-Greeting"},
+		},
+		{
+			name:            "add-header! keeps existing values",
+			call:            func(m *mutator) (any, error) { return m.addHeaderFunc("X-Foo", "three") },
+			header:          "X-Foo",
+			expected:        []string{"one", "two", "three"},
+			expectedResult:  "three",
+			expectedChanges: []string{"add X-Foo"},
+		},
+		{
+			name:            "add-header! encodes non-ASCII values",
+			call:            func(m *mutator) (any, error) { return m.addHeaderFunc("x-greeting", "Grüße") },
+			header:          "X-Greeting",
+			expected:        []string{"=?utf-8?q?Gr=C3=BC=C3=9Fe?="},
+			expectedResult:  "Grüße",
+			expectedChanges: []string{"add X-Greeting"},
+		},
+		{
+			name:            "remove-header! removes all values",
+			call:            func(m *mutator) (any, error) { return m.removeHeaderFunc("x-foo") },
+			header:          "X-Foo",
+			expected:        nil,
+			expectedResult:  true,
+			expectedChanges: []string{"remove X-Foo"},
+		},
+		{
+			name:           "remove-header! ignores missing headers",
+			call:           func(m *mutator) (any, error) { return m.removeHeaderFunc("X-Bar") },
+			header:         "X-Bar",
+			expected:       nil,
+			expectedResult: false,
+		},
+		{
+			name:            "prefix-subject! prefixes the subject",
+			call:            func(m *mutator) (any, error) { return m.prefixSubjectFunc("[list] ") },
+			header:          "Subject",
+			expected:        []string{"[list] Hello"},
+			expectedResult:  "[list] Hello",
+			expectedChanges: []string{"prefix Subject"},
+		},
+		{
+			name:           "prefix-subject! keeps already prefixed subjects",
+			call:           func(m *mutator) (any, error) { return m.prefixSubjectFunc("Hell") },
+			header:         "Subject",
+			expected:       []string{"Hello"},
+			expectedResult: "Hello",
+		},
+		{
+			name:            "prefix-subject! encodes non-ASCII subjects",
+			call:            func(m *mutator) (any, error) { return m.prefixSubjectFunc("Ä ") },
+			header:          "Subject",
+			expected:        []string{"=?utf-8?q?=C3=84_Hello?="},
+			expectedResult:  "Ä Hello",
+			expectedChanges: []string{"prefix Subject"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			msg, err := email.ParseMessage([]byte(headerMessage))
+			if err != nil {
+				t.Fatalf("Failed to parse message: %v", err)
+			}
+
+			m := newMutator(msg)
+
+			result, err := testcase.call(m)
+			if err != nil {
+				t.Fatalf("Function failed: %v", err)
+			}
+
+			if !cmp.Equal(testcase.expectedResult, result) {
+				t.Errorf("Expected result %#v, got %#v.", testcase.expectedResult, result)
+			}
+
+			if values := msg.Header[testcase.header]; !cmp.Equal(testcase.expected, values) {
+				t.Errorf("Expected %s to be %q, got %q.", testcase.header, testcase.expected, values)
+			}
+
+			if !cmp.Equal(testcase.expectedChanges, m.changes) {
+				t.Errorf("Expected changes %v, got %v.", testcase.expectedChanges, m.changes)
+			}
+		})
+	}
+}
+
+const multipartMessage = "Subject: Report\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"XYZ\"\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"See attachments.\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: image/png; name=\"chart.png\"\r\n" +
+	"\r\n" +
+	"PNGDATA\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: application/pdf; name=\"report.pdf\"\r\n" +
+	"\r\n" +
+	"PDFDATAPDFDATAPDFDATA\r\n" +
+	"--XYZ--\r\n"
+
+func TestStripPart(t *testing.T) {
+	testcases := []struct {
+		contentType     string
+		minSize         int64
+		expected        int64
+		expectedChanges []string
+	}{
+		{
+			contentType:     "image/*",
+			expected:        1,
+			expectedChanges: []string{"strip image/*"},
+		},
+		{
+			contentType:     "IMAGE/PNG",
+			expected:        1,
+			expectedChanges: []string{"strip IMAGE/PNG"},
+		},
+		{
+			contentType:     "*/*",
+			minSize:         20,
+			expected:        1,
+			expectedChanges: []string{"strip */*"},
+		},
+		{
+			contentType: "application/pdf",
+			minSize:     1000,
+			expected:    0,
+		},
+		{
+			contentType: "video/*",
+			expected:    0,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.contentType, func(t *testing.T) {
+			msg, err := email.ParseMessage([]byte(multipartMessage))
+			if err != nil {
+				t.Fatalf("Failed to parse message: %v", err)
+			}
+
+			m := newMutator(msg)
+
+			result, err := m.stripPartWithSizeFunc(testcase.contentType, testcase.minSize)
+			if err != nil {
+				t.Fatalf("Function failed: %v", err)
+			}
+
+			if result != testcase.expected {
+				t.Errorf("Expected %d stripped parts, got %v.", testcase.expected, result)
+			}
+
+			if !cmp.Equal(testcase.expectedChanges, m.changes) {
+				t.Errorf("Expected changes %v, got %v.", testcase.expectedChanges, m.changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rewrite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+)
+
+type Proc struct {
+	scriptFile string
+}
+
+func New(scriptFile string) *Proc {
+	return &Proc{
+		scriptFile: scriptFile,
+	}
+}
+
+func (*Proc) Name() string {
+	return "rewrite"
+}
+
+func (p *Proc) Process(ctx context.Context, logger logrus.FieldLogger, msg *email.Message, _ *metrics.Metrics) (consumed bool, updated *email.Message, err error) {
+	m := newMutator(msg)
+
+	if _, err := rudilib.ProcessMessage(ctx, p.scriptFile, msg, nil, nil, m.functions()); err != nil {
+		// changes made before the script failed are kept
+		return false, msg, fmt.Errorf("script failed: %w", err)
+	}
+
+	if len(m.changes) > 0 {
+		logger.WithField("changes", m.changes).Info("Rewrote e-mail.")
+		processor.Notef(ctx, "rewrite script: %v", m.changes)
+	}
+
+	return false, msg, nil
+}
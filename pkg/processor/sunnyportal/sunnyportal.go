@@ -7,7 +7,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -17,8 +16,8 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
-	"go.xrstf.de/rudi-lda/pkg/fs"
 	"go.xrstf.de/rudi-lda/pkg/metrics"
+	"go.xrstf.de/rudi-lda/pkg/processor"
 )
 
 // Subject: Sunny Portal Info Report Fam. Mewes 1/29/2022 Daily Production: 0
@@ -46,7 +45,7 @@ func (*Proc) Name() string {
 	return "sunnyportal"
 }
 
-func (p *Proc) Process(_ context.Context, logger logrus.FieldLogger, msg *email.Message, _ *metrics.Metrics) (consumed bool, updated *email.Message, err error) {
+func (p *Proc) Process(ctx context.Context, logger logrus.FieldLogger, msg *email.Message, _ *metrics.Metrics) (consumed bool, updated *email.Message, err error) {
 	if !subjectRegex.MatchString(msg.GetSubject()) {
 		return false, msg, nil
 	}
@@ -59,19 +58,15 @@ func (p *Proc) Process(_ context.Context, logger logrus.FieldLogger, msg *email.
 	}
 
 	logFile := filepath.Join(p.datadir, "sunnyportal.csv")
-	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fs.FilePermissions)
-	if err != nil {
-		return false, msg, fmt.Errorf("failed to open data file: %w", err)
-	}
-	defer f.Close()
-
-	if _, err := f.WriteString(fmt.Sprintf(
+	line := fmt.Sprintf(
 		"%s;%.4F kWh;%.4F EUR;%.4F kg\n",
 		info.time.Format(time.RFC3339),
 		info.production,
 		info.revenue,
 		info.co2,
-	)); err != nil {
+	)
+
+	if err := processor.GetEffects(ctx).AppendFile(logFile, []byte(line)); err != nil {
 		return false, msg, fmt.Errorf("failed to append data: %w", err)
 	}
 
@@ -0,0 +1,215 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type Decision string
+
+const (
+	Consumed Decision = "consumed"
+	Passed   Decision = "passed"
+	Failed   Decision = "error"
+)
+
+// Trace records what each processor in a pipeline did with a message.
+type Trace struct {
+	Steps []*Step `json:"steps"`
+	// Destination is where the message ended up, e.g. a Maildir folder.
+	Destination string `json:"destination"`
+}
+
+// Step is the trace of a single processor.
+type Step struct {
+	Processor string         `json:"processor"`
+	Headers   mail.Header    `json:"headers"`
+	Decision  Decision       `json:"decision"`
+	Error     string         `json:"error,omitempty"`
+	Changes   []HeaderChange `json:"changes,omitempty"`
+	// Notes are things like the return values of scripts.
+	Notes   []string `json:"notes,omitempty"`
+	Effects []string `json:"effects,omitempty"`
+}
+
+// HeaderChange is a header that was added, removed or changed by a processor.
+type HeaderChange struct {
+	Header string   `json:"header"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that makes the pipeline record its steps in
+// the given trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// GetTrace returns the trace from the context, if any.
+func GetTrace(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// Notef adds a note to the current step of the trace (if tracing).
+func Notef(ctx context.Context, format string, args ...any) {
+	if step := GetTrace(ctx).current(); step != nil {
+		step.Notes = append(step.Notes, fmt.Sprintf(format, args...))
+	}
+}
+
+// SetDestination records where the message ended up (if tracing).
+func SetDestination(ctx context.Context, destination string) {
+	if trace := GetTrace(ctx); trace != nil {
+		trace.Destination = destination
+	}
+}
+
+func (t *Trace) current() *Step {
+	if t == nil || len(t.Steps) == 0 {
+		return nil
+	}
+
+	return t.Steps[len(t.Steps)-1]
+}
+
+func (t *Trace) effect(description string) {
+	if step := t.current(); step != nil {
+		step.Effects = append(step.Effects, description)
+	}
+}
+
+func (t *Trace) begin(name string, header mail.Header) {
+	if t == nil {
+		return
+	}
+
+	t.Steps = append(t.Steps, &Step{
+		Processor: name,
+		Headers:   cloneHeader(header),
+	})
+}
+
+func (t *Trace) end(consumed bool, err error, header mail.Header) {
+	step := t.current()
+	if step == nil {
+		return
+	}
+
+	switch {
+	case err != nil:
+		step.Decision = Failed
+		step.Error = err.Error()
+	case consumed:
+		step.Decision = Consumed
+	default:
+		step.Decision = Passed
+	}
+
+	if consumed && t.Destination == "" {
+		t.Destination = fmt.Sprintf("(consumed by %s)", step.Processor)
+	}
+
+	step.Changes = diffHeaders(step.Headers, header)
+}
+
+func diffHeaders(before mail.Header, after mail.Header) []HeaderChange {
+	var changes []HeaderChange
+
+	for key, values := range before {
+		if !slices.Equal(values, after[key]) {
+			changes = append(changes, HeaderChange{Header: key, Before: values, After: after[key]})
+		}
+	}
+
+	for key, values := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, HeaderChange{Header: key, After: values})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Header < changes[j].Header
+	})
+
+	return changes
+}
+
+func cloneHeader(h mail.Header) mail.Header {
+	clone := make(mail.Header, len(h))
+	for key, values := range h {
+		clone[key] = slices.Clone(values)
+	}
+
+	return clone
+}
+
+// Print writes the trace in a human readable form. The input headers are
+// only printed for the first step, later steps only list their changes.
+func (t *Trace) Print(w io.Writer) {
+	for i, step := range t.Steps {
+		if i == 0 {
+			fmt.Fprintln(w, "input headers:")
+
+			keys := make([]string, 0, len(step.Headers))
+			for key := range step.Headers {
+				keys = append(keys, key)
+			}
+
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				for _, value := range step.Headers[key] {
+					fmt.Fprintf(w, "  %s: %s\n", key, value)
+				}
+			}
+
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "%s: %s", step.Processor, step.Decision)
+		if step.Error != "" {
+			fmt.Fprintf(w, " (%s)", step.Error)
+		}
+		fmt.Fprintln(w)
+
+		for _, change := range step.Changes {
+			for _, value := range change.Before {
+				fmt.Fprintf(w, "  - %s: %s\n", change.Header, value)
+			}
+
+			for _, value := range change.After {
+				fmt.Fprintf(w, "  + %s: %s\n", change.Header, value)
+			}
+		}
+
+		for _, note := range step.Notes {
+			fmt.Fprintf(w, "  note: %s\n", indent(note))
+		}
+
+		for _, effect := range step.Effects {
+			fmt.Fprintf(w, "  effect: %s\n", effect)
+		}
+	}
+
+	destination := t.Destination
+	if destination == "" {
+		destination = "(none)"
+	}
+
+	fmt.Fprintf(w, "\ndestination: %s\n", destination)
+}
+
+func indent(s string) string {
+	return strings.ReplaceAll(s, "\n", "\n        ")
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/metrics"
+)
+
+type fakeProc struct {
+	name    string
+	file    string
+	consume bool
+	err     error
+}
+
+func (p *fakeProc) Name() string {
+	return p.name
+}
+
+func (p *fakeProc) Process(ctx context.Context, _ logrus.FieldLogger, msg *email.Message, _ *metrics.Metrics) (bool, *email.Message, error) {
+	msg.Header["X-"+p.name] = []string{"yes"}
+
+	Notef(ctx, "hello from %s", p.name)
+
+	if p.file != "" {
+		if err := GetEffects(ctx).AppendFile(p.file, []byte("data\n")); err != nil {
+			return false, msg, err
+		}
+	}
+
+	if p.consume {
+		SetDestination(ctx, "Archive")
+		return true, nil, nil
+	}
+
+	return false, msg, p.err
+}
+
+func TestDryRunTrace(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "data.csv")
+
+	msg, err := email.ParseMessage([]byte("Subject: test\r\n\r\nBody\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	processors := []Processor{
+		&fakeProc{name: "first", file: dataFile},
+		&fakeProc{name: "second", err: errors.New("oops")},
+		&fakeProc{name: "third", consume: true},
+		&fakeProc{name: "never"},
+	}
+
+	trace := &Trace{}
+	ctx := WithTrace(context.Background(), trace)
+	ctx = WithEffects(ctx, NewDryRun(trace))
+
+	metricsData, _ := metrics.Load("")
+
+	if _, err := Pipeline(ctx, logrus.New(), processors, msg, metricsData); err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+
+	if _, err := os.Stat(dataFile); !os.IsNotExist(err) {
+		t.Errorf("Dry-run should not have created %s.", dataFile)
+	}
+
+	if len(trace.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d.", len(trace.Steps))
+	}
+
+	expected := []Decision{Passed, Failed, Consumed}
+	for i, step := range trace.Steps {
+		if step.Decision != expected[i] {
+			t.Errorf("Expected step %d to be %q, got %q.", i, expected[i], step.Decision)
+		}
+
+		if len(step.Notes) != 1 {
+			t.Errorf("Expected step %d to have one note, got %v.", i, step.Notes)
+		}
+	}
+
+	first := trace.Steps[0]
+	if len(first.Effects) != 1 || len(first.Changes) != 1 || first.Changes[0].Header != "X-first" {
+		t.Errorf("Unexpected first step: %+v", first)
+	}
+
+	// the second processor sees the header set by the first one
+	if _, ok := trace.Steps[1].Headers["X-first"]; !ok {
+		t.Error("Second step should have seen the header set by the first processor.")
+	}
+
+	if trace.Destination != "Archive" {
+		t.Errorf("Expected destination Archive, got %q.", trace.Destination)
+	}
+}
+
+func TestDirectEffects(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "sub", "data.csv")
+	effects := GetEffects(context.Background())
+
+	for i := 0; i < 2; i++ {
+		if err := effects.AppendFile(dataFile, []byte("line\n")); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(dataFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	if string(content) != "line\nline\n" {
+		t.Errorf("Unexpected file content %q.", content)
+	}
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package quarantine
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// Pending returns the entries that were quarantined since the last digest,
+// grouped by user.
+func (q *Quarantine) Pending() (map[string][]Entry, error) {
+	d, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := map[string][]Entry{}
+	for _, entry := range d.Entries {
+		if entry.Quarantined.After(d.Digests[entry.User]) {
+			pending[entry.User] = append(pending[entry.User], entry)
+		}
+	}
+
+	for _, entries := range pending {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Quarantined.Before(entries[j].Quarantined)
+		})
+	}
+
+	return pending, nil
+}
+
+// MarkDigested records that the user has received a digest of all
+// entries up to the given entry.
+func (q *Quarantine) MarkDigested(user string, last Entry) error {
+	return q.update(func(d *data) error {
+		if last.Quarantined.After(d.Digests[user]) {
+			d.Digests[user] = last.Quarantined
+		}
+
+		return nil
+	})
+}
+
+var digestText = template.Must(template.New("text").Parse(`{{ len .Entries }} e-mail(s) to {{ .User }} have been quarantined as spam:
+{{ range .Entries }}
+* {{ .Subject }}
+  From: {{ .From }}
+  Date: {{ .Quarantined.Format "2006-01-02 15:04" }}
+  Rule: {{ .Rule }}
+  ID:   {{ .ID }}
+{{ end }}
+Quarantined e-mails are deleted after some time. To get an e-mail
+back, ask your administrator to release it using its ID.
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<p>{{ len .Entries }} e-mail(s) to {{ .User }} have been quarantined as spam:</p>
+<table>
+<tr><th>Date</th><th>From</th><th>Subject</th><th>Rule</th><th>ID</th></tr>
+{{- range .Entries }}
+<tr><td>{{ .Quarantined.Format "2006-01-02 15:04" }}</td><td>{{ .From }}</td><td>{{ .Subject }}</td><td>{{ .Rule }}</td><td><code>{{ .ID }}</code></td></tr>
+{{- end }}
+</table>
+<p>Quarantined e-mails are deleted after some time. To get an e-mail back, ask your administrator to release it using its ID.</p>
+</body>
+</html>
+`))
+
+// BuildDigest creates the digest message for a user, with a text and an
+// HTML part.
+func BuildDigest(from string, user string, entries []Entry, now time.Time) (*email.Message, error) {
+	data := map[string]any{
+		"User":    user,
+		"Entries": entries,
+	}
+
+	var text, html bytes.Buffer
+
+	if err := digestText.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text: %w", err)
+	}
+
+	if err := digestHTML.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML: %w", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     []byte
+	}{
+		{contentType: "text/plain; charset=utf-8", content: text.Bytes()},
+		{contentType: "text/html; charset=utf-8", content: html.Bytes()},
+	}
+
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", p.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(part)
+		if _, err := encoder.Write(crlf(p.content)); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	subject := fmt.Sprintf("Quarantine digest: %d new spam e-mail(s)", len(entries))
+
+	var raw bytes.Buffer
+	fmt.Fprintf(&raw, "From: %s\r\n", from)
+	fmt.Fprintf(&raw, "To: %s\r\n", (&mail.Address{Address: user}).String())
+	fmt.Fprintf(&raw, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&raw, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&raw, "Message-ID: <digest.%d.%s@%s>\r\n", now.UnixNano(), strings.ReplaceAll(user, "@", "."), hostname)
+	fmt.Fprintf(&raw, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&raw, "Content-Type: multipart/alternative; boundary=%q\r\n", writer.Boundary())
+	fmt.Fprintf(&raw, "\r\n")
+	raw.Write(body.Bytes())
+
+	return email.ParseMessage(raw.Bytes())
+}
+
+func crlf(content []byte) []byte {
+	return bytes.ReplaceAll(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package quarantine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPending(t *testing.T) {
+	q := New(t.TempDir(), time.Hour)
+
+	first, err := q.Add(parse(t, "Subject: first\r\n\r\nBody\r\n"), "alice", "test", 0)
+	if err != nil {
+		t.Fatalf("Failed to add message: %v", err)
+	}
+
+	if _, err := q.Add(parse(t, "Subject: other\r\n\r\nBody\r\n"), "bob", "test", 0); err != nil {
+		t.Fatalf("Failed to add message: %v", err)
+	}
+
+	pending, err := q.Pending()
+	if err != nil {
+		t.Fatalf("Failed to get pending entries: %v", err)
+	}
+
+	if len(pending["alice"]) != 1 || len(pending["bob"]) != 1 {
+		t.Fatalf("Expected one entry per user, got %+v.", pending)
+	}
+
+	if err := q.MarkDigested("alice", *first); err != nil {
+		t.Fatalf("Failed to record digest: %v", err)
+	}
+
+	second, err := q.Add(parse(t, "Subject: second\r\n\r\nBody\r\n"), "alice", "test", 0)
+	if err != nil {
+		t.Fatalf("Failed to add message: %v", err)
+	}
+
+	// entries can be added within the same clock tick, so make sure the
+	// second one is newer than the digest
+	setQuarantined(t, q, second.ID, first.Quarantined.Add(time.Minute))
+
+	pending, err = q.Pending()
+	if err != nil {
+		t.Fatalf("Failed to get pending entries: %v", err)
+	}
+
+	if len(pending["alice"]) != 1 || pending["alice"][0].ID != second.ID {
+		t.Fatalf("Expected only the second entry for alice, got %+v.", pending["alice"])
+	}
+
+	if len(pending["bob"]) != 1 {
+		t.Fatalf("Expected bob's entry to be still pending, got %+v.", pending["bob"])
+	}
+}
+
+func setQuarantined(t *testing.T, q *Quarantine, id string, quarantined time.Time) {
+	t.Helper()
+
+	err := q.update(func(d *data) error {
+		entry := d.Entries[id]
+		entry.Quarantined = quarantined
+		d.Entries[id] = entry
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to update index: %v", err)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	entries := []Entry{
+		{
+			ID:          "20240102_150405_1234",
+			From:        "Spammer <spam@example.com>",
+			Subject:     "Cheap <b>pills</b> für dich",
+			Rule:        "bayes",
+			Quarantined: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+	}
+
+	msg, err := BuildDigest("rudi-lda@example.com", "alice@example.com", entries, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to build digest: %v", err)
+	}
+
+	if to := msg.GetTo(); to == nil || to.Address != "alice@example.com" {
+		t.Errorf("Unexpected recipient %v.", to)
+	}
+
+	if subject := msg.GetSubject(); !strings.Contains(subject, "1 new spam") {
+		t.Errorf("Unexpected subject %q.", subject)
+	}
+
+	parts, err := msg.Parts()
+	if err != nil {
+		t.Fatalf("Failed to get parts: %v", err)
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d.", len(parts))
+	}
+
+	text := string(parts[0].Body)
+	if parts[0].ContentType != "text/plain" || !strings.Contains(text, "Cheap <b>pills</b> für dich") || !strings.Contains(text, entries[0].ID) {
+		t.Errorf("Unexpected text part:\n%s", text)
+	}
+
+	html := string(parts[1].Body)
+	if parts[1].ContentType != "text/html" || !strings.Contains(html, "Cheap &lt;b&gt;pills&lt;/b&gt; für dich") {
+		t.Errorf("Unexpected HTML part:\n%s", html)
+	}
+}
@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+var ErrNotFound = errors.New("no such quarantined e-mail")
+
+// Entry is what the index remembers about a quarantined message.
+type Entry struct {
+	ID          string    `json:"id"`
+	User        string    `json:"user"`
+	MessageID   string    `json:"messageID,omitempty"`
+	From        string    `json:"from"`
+	Subject     string    `json:"subject"`
+	Rule        string    `json:"rule"`
+	Score       float64   `json:"score,omitempty"`
+	Date        time.Time `json:"date"`
+	Quarantined time.Time `json:"quarantined"`
+}
+
+type data struct {
+	Entries map[string]Entry `json:"entries"`
+	// Digests maps users to the time of the last entry that was included
+	// in their last digest.
+	Digests map[string]time.Time `json:"digests,omitempty"`
+}
+
+// Quarantine stores discarded messages together with an index, so that
+// false positives can be found and released again. Messages are removed
+// after the retention. It is safe to be used by concurrent processes.
+type Quarantine struct {
+	directory string
+	retention time.Duration
+}
+
+func New(directory string, retention time.Duration) *Quarantine {
+	return &Quarantine{
+		directory: directory,
+		retention: retention,
+	}
+}
+
+// Directory returns the location of the quarantine inside the datadir.
+func Directory(datadir string) string {
+	return filepath.Join(datadir, "quarantine")
+}
+
+// MessageDirectory returns the directory that contains the quarantined
+// messages (one file per message).
+func (q *Quarantine) MessageDirectory() string {
+	return filepath.Join(q.directory, "messages")
+}
+
+func (q *Quarantine) indexFile() string {
+	return filepath.Join(q.directory, "index.json")
+}
+
+func (q *Quarantine) messageFile(id string) string {
+	return filepath.Join(q.MessageDirectory(), id+".eml")
+}
+
+// Add stores a message for the given destination user. Expired messages
+// are removed at the same time.
+func (q *Quarantine) Add(msg *email.Message, user string, rule string, score float64) (*Entry, error) {
+	id, err := q.store(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store e-mail: %w", err)
+	}
+
+	entry := Entry{
+		ID:          id,
+		User:        user,
+		MessageID:   msg.GetMessageID(),
+		Subject:     msg.GetSubject(),
+		Rule:        rule,
+		Score:       score,
+		Quarantined: time.Now(),
+	}
+
+	if from := msg.GetFrom(); from != nil {
+		entry.From = from.Address
+		if from.Name != "" {
+			entry.From = fmt.Sprintf("%s <%s>", from.Name, from.Address)
+		}
+	}
+
+	if date, err := msg.GetDate(); err == nil {
+		entry.Date = date
+	}
+
+	err = q.update(func(d *data) error {
+		d.Entries[entry.ID] = entry
+		return nil
+	})
+	if err != nil {
+		os.Remove(q.messageFile(id))
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// store writes the message into a new file and returns its ID.
+func (q *Quarantine) store(msg *email.Message) (string, error) {
+	if err := os.MkdirAll(q.MessageDirectory(), fs.DirectoryPermissions); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	base := strings.TrimSuffix(fs.UniqueEmailFilename(), ".eml")
+
+	for i := 0; ; i++ {
+		id := base
+		if i > 0 {
+			id = fmt.Sprintf("%s_%d", base, i)
+		}
+
+		f, err := os.OpenFile(q.messageFile(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, fs.FilePermissions)
+		if err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+
+			return "", err
+		}
+
+		if _, err := f.Write(msg.Raw()); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+
+		return id, f.Close()
+	}
+}
+
+// List returns all entries, optionally only for a single user, the oldest
+// first.
+func (q *Quarantine) List(user string) ([]Entry, error) {
+	d, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, entry := range d.Entries {
+		if user == "" || entry.User == user {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Quarantined.Equal(entries[j].Quarantined) {
+			return entries[i].ID < entries[j].ID
+		}
+
+		return entries[i].Quarantined.Before(entries[j].Quarantined)
+	})
+
+	return entries, nil
+}
+
+// Get returns an entry and its message.
+func (q *Quarantine) Get(id string) (*Entry, *email.Message, error) {
+	d, err := q.load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry, ok := d.Entries[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	content, err := os.ReadFile(q.messageFile(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read e-mail: %w", err)
+	}
+
+	msg, err := email.ParseMessage(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &entry, msg, nil
+}
+
+// Delete removes a message from the quarantine.
+func (q *Quarantine) Delete(id string) error {
+	return q.update(func(d *data) error {
+		if _, ok := d.Entries[id]; !ok {
+			return ErrNotFound
+		}
+
+		delete(d.Entries, id)
+
+		return nil
+	})
+}
+
+// Expire removes all expired messages. This also happens automatically
+// whenever messages are added.
+func (q *Quarantine) Expire() error {
+	return q.update(func(*data) error {
+		return nil
+	})
+}
+
+func (q *Quarantine) load() (*data, error) {
+	d := &data{
+		Entries: map[string]Entry{},
+		Digests: map[string]time.Time{},
+	}
+
+	content, err := os.ReadFile(q.indexFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+
+		return nil, fmt.Errorf("failed to read quarantine index: %w", err)
+	}
+
+	if err := json.Unmarshal(content, d); err != nil {
+		return nil, fmt.Errorf("failed to decode quarantine index: %w", err)
+	}
+
+	if d.Entries == nil {
+		d.Entries = map[string]Entry{}
+	}
+
+	if d.Digests == nil {
+		d.Digests = map[string]time.Time{}
+	}
+
+	return d, nil
+}
+
+func (q *Quarantine) update(mutate func(d *data) error) error {
+	unlock, err := fs.LockFile(q.indexFile() + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock quarantine index: %w", err)
+	}
+	defer unlock()
+
+	d, err := q.load()
+	if err != nil {
+		return err
+	}
+
+	before := make(map[string]struct{}, len(d.Entries))
+	for id := range d.Entries {
+		before[id] = struct{}{}
+	}
+
+	if err := mutate(d); err != nil {
+		return err
+	}
+
+	q.expire(d)
+
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantine index: %w", err)
+	}
+
+	if err := fs.WriteFileAtomic(q.indexFile(), encoded); err != nil {
+		return err
+	}
+
+	// only remove files once they are no longer referenced
+	for id := range before {
+		if _, ok := d.Entries[id]; !ok {
+			if err := os.Remove(q.messageFile(id)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove e-mail: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (q *Quarantine) expire(d *data) {
+	if q.retention <= 0 {
+		return
+	}
+
+	threshold := time.Now().Add(-q.retention)
+
+	for id, entry := range d.Entries {
+		if entry.Quarantined.Before(threshold) {
+			delete(d.Entries, id)
+		}
+	}
+}
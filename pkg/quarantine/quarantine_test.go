@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package quarantine
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+func parse(t *testing.T, raw string) *email.Message {
+	t.Helper()
+
+	msg, err := email.ParseMessage([]byte(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	return msg
+}
+
+func TestQuarantine(t *testing.T) {
+	q := New(t.TempDir(), time.Hour)
+
+	first, err := q.Add(parse(t, "From: Spammer <spam@example.com>\r\nSubject: Buy now\r\nDate: Mon, 2 Jan 2006 15:04:05 -0700\r\n\r\nBody\r\n"), "alice", "bayes", 12.5)
+	if err != nil {
+		t.Fatalf("Failed to add message: %v", err)
+	}
+
+	if first.From != "Spammer <spam@example.com>" || first.Subject != "Buy now" || first.Rule != "bayes" || first.Date.IsZero() {
+		t.Errorf("Unexpected entry: %+v", first)
+	}
+
+	second, err := q.Add(parse(t, "From: other@example.com\r\nSubject: Hello\r\n\r\nBody\r\n"), "bob", "dnsbl", 0)
+	if err != nil {
+		t.Fatalf("Failed to add message: %v", err)
+	}
+
+	if first.ID == second.ID {
+		t.Fatalf("Both messages got the same ID %q.", first.ID)
+	}
+
+	entries, err := q.List("")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d.", len(entries))
+	}
+
+	entries, err = q.List("bob")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].ID != second.ID {
+		t.Fatalf("Expected only bob's entry, got %+v.", entries)
+	}
+
+	entry, msg, err := q.Get(first.ID)
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+
+	if entry.User != "alice" || msg.GetSubject() != "Buy now" {
+		t.Errorf("Got wrong message: %+v", entry)
+	}
+
+	if err := q.Delete(first.ID); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+
+	if _, err := os.Stat(q.messageFile(first.ID)); !os.IsNotExist(err) {
+		t.Error("Message file should have been removed.")
+	}
+
+	if _, _, err := q.Get(first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v.", err)
+	}
+
+	if err := q.Delete("../index"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown ID, got %v.", err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	q := New(t.TempDir(), time.Hour)
+
+	old, err := q.Add(parse(t, "Subject: old\r\n\r\nBody\r\n"), "alice", "test", 0)
+	if err != nil {
+		t.Fatalf("Failed to add message: %v", err)
+	}
+
+	// backdate the entry
+	err = q.update(func(d *data) error {
+		entry := d.Entries[old.ID]
+		entry.Quarantined = time.Now().Add(-2 * time.Hour)
+		d.Entries[old.ID] = entry
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to update index: %v", err)
+	}
+
+	if err := q.Expire(); err != nil {
+		t.Fatalf("Failed to expire: %v", err)
+	}
+
+	entries, err := q.List("")
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("Expected no entries, got %+v.", entries)
+	}
+
+	if _, err := os.Stat(q.messageFile(old.ID)); !os.IsNotExist(err) {
+		t.Error("Expired message file should have been removed.")
+	}
+}
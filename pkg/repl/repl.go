@@ -0,0 +1,398 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package repl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+	"go.xrstf.de/rudi-lda/pkg/rudilib"
+	"go.xrstf.de/rudi-lda/pkg/spam"
+)
+
+const help = `Enter Rudi expressions to evaluate them against the current e-mail, e.g.
+(domain .from.address). Expressions can span multiple lines.
+
+  :open FILE|ID   open an e-mail file (or an e-mail from the Maildir by its ID)
+  :messages       list all opened e-mails
+  :switch N       switch to the N-th opened e-mail
+  :load FILE      evaluate a script file against the current e-mail
+  :doc [NAME]     describe a function (or list all functions)
+  :history        list previous inputs, !N repeats input N, !! the last one
+  :help           print this help
+  :quit           exit (or Ctrl-D)
+`
+
+type message struct {
+	name string
+	msg  *email.Message
+}
+
+// Session is an interactive session for evaluating Rudi expressions against
+// e-mails, using the same functions as the spam and folder scripts. Scores
+// added with score! only count for a single input.
+type Session struct {
+	dir         string
+	maildir     *maildir.Maildir
+	historyFile string
+
+	messages []message
+	current  int
+	history  []string
+}
+
+// New creates a session. Files used by expressions are looked up in dir.
+func New(dir string) *Session {
+	return &Session{
+		dir: dir,
+	}
+}
+
+// WithMaildir allows to open e-mails from the Maildir by their ID (the
+// unique part of their filename).
+func (s *Session) WithMaildir(md *maildir.Maildir) *Session {
+	s.maildir = md
+	return s
+}
+
+// WithHistory keeps the history of all inputs in the given file.
+func (s *Session) WithHistory(filename string) *Session {
+	s.historyFile = filename
+	return s
+}
+
+// Open loads an e-mail and makes it the current one.
+func (s *Session) Open(ref string) error {
+	filename := ref
+
+	if _, err := os.Stat(ref); err != nil {
+		if s.maildir == nil {
+			return err
+		}
+
+		filename, err = s.maildir.Find(ref)
+		if err != nil {
+			return err
+		}
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read e-mail: %w", err)
+	}
+
+	msg, err := email.ParseMessage(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse e-mail: %w", err)
+	}
+
+	s.messages = append(s.messages, message{name: ref, msg: msg})
+	s.current = len(s.messages) - 1
+
+	return nil
+}
+
+// Run reads inputs until the input is exhausted or :quit is entered.
+func (s *Session) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	if err := s.loadHistory(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	pending := ""
+
+	for {
+		if pending == "" {
+			fmt.Fprint(out, s.prompt())
+		} else {
+			fmt.Fprint(out, "... ")
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		pending = strings.TrimSpace(pending + "\n" + scanner.Text())
+		if pending == "" || !balanced(pending) {
+			continue
+		}
+
+		input := pending
+		pending = ""
+
+		input, err := s.expandHistory(input)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+
+		if input == ":quit" || input == ":q" {
+			return nil
+		}
+
+		if err := s.remember(input); err != nil {
+			fmt.Fprintf(out, "warning: %v\n", err)
+		}
+
+		if err := s.handle(ctx, input, out); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+func (s *Session) prompt() string {
+	if len(s.messages) == 0 {
+		return "rudi> "
+	}
+
+	return fmt.Sprintf("rudi[%d]> ", s.current+1)
+}
+
+func (s *Session) handle(ctx context.Context, input string, out io.Writer) error {
+	if !strings.HasPrefix(input, ":") {
+		msg, err := s.currentMessage()
+		if err != nil {
+			return err
+		}
+
+		result, err := rudilib.Evaluate(ctx, input, s.dir, msg, nil, nil, spam.ScriptFunctions())
+		if err != nil {
+			return err
+		}
+
+		printResult(out, result)
+
+		return nil
+	}
+
+	command, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case ":help", ":h":
+		fmt.Fprint(out, help)
+
+	case ":open":
+		if arg == "" {
+			return errors.New("usage: :open FILE|ID")
+		}
+
+		if err := s.Open(arg); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "[%d] %s\n", s.current+1, s.messages[s.current].msg.GetSubject())
+
+	case ":messages":
+		for i, m := range s.messages {
+			marker := " "
+			if i == s.current {
+				marker = "*"
+			}
+
+			fmt.Fprintf(out, "%s[%d] %s (%s)\n", marker, i+1, m.msg.GetSubject(), m.name)
+		}
+
+	case ":switch":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(s.messages) {
+			return fmt.Errorf("usage: :switch N (1-%d)", len(s.messages))
+		}
+
+		s.current = n - 1
+
+	case ":load":
+		if arg == "" {
+			return errors.New("usage: :load FILE")
+		}
+
+		msg, err := s.currentMessage()
+		if err != nil {
+			return err
+		}
+
+		result, err := rudilib.ProcessMessage(ctx, arg, msg, nil, nil, spam.ScriptFunctions())
+		if err != nil {
+			return err
+		}
+
+		printResult(out, result)
+
+	case ":doc":
+		return s.doc(ctx, arg, out)
+
+	case ":history":
+		for i, entry := range s.history {
+			fmt.Fprintf(out, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+		}
+
+	default:
+		return fmt.Errorf("unknown command %q, see :help", command)
+	}
+
+	return nil
+}
+
+func (s *Session) currentMessage() (*email.Message, error) {
+	if len(s.messages) == 0 {
+		return nil, errors.New("no e-mail opened yet, use :open FILE|ID")
+	}
+
+	return s.messages[s.current].msg, nil
+}
+
+func (s *Session) doc(ctx context.Context, name string, out io.Writer) error {
+	msg, err := s.currentMessage()
+	if err != nil {
+		// the functions do not depend on the message content
+		msg = &email.Message{}
+	}
+
+	funcs := rudilib.AllFunctions(ctx, filepath.Join(s.dir, "repl"), msg, spam.ScriptFunctions())
+
+	if name == "" {
+		names := make([]string, 0, len(funcs))
+		for name := range funcs {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		fmt.Fprintln(out, strings.Join(names, " "))
+
+		return nil
+	}
+
+	fn, ok := funcs[name]
+	if !ok {
+		return fmt.Errorf("unknown function %q", name)
+	}
+
+	fmt.Fprintf(out, "%s: %s\n", name, fn.Description())
+
+	return nil
+}
+
+// expandHistory replaces "!!" and "!N" with the respective history entry.
+func (s *Session) expandHistory(input string) (string, error) {
+	if !strings.HasPrefix(input, "!") {
+		return input, nil
+	}
+
+	if len(s.history) == 0 {
+		return "", errors.New("history is empty")
+	}
+
+	if input == "!!" {
+		return s.history[len(s.history)-1], nil
+	}
+
+	n, err := strconv.Atoi(input[1:])
+	if err != nil || n < 1 || n > len(s.history) {
+		return "", fmt.Errorf("no history entry %s", input[1:])
+	}
+
+	return s.history[n-1], nil
+}
+
+func (s *Session) loadHistory() error {
+	if s.historyFile == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(s.historyFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	// entries are JSON-encoded to support multi-line inputs
+	for _, line := range strings.Split(string(content), "\n") {
+		var entry string
+		if err := json.Unmarshal([]byte(line), &entry); err == nil {
+			s.history = append(s.history, entry)
+		}
+	}
+
+	return nil
+}
+
+func (s *Session) remember(input string) error {
+	s.history = append(s.history, input)
+
+	if s.historyFile == "" {
+		return nil
+	}
+
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.historyFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(encoded, '\n'))
+
+	return err
+}
+
+func printResult(out io.Writer, result any) {
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(out, "%v\n", result)
+		return
+	}
+
+	fmt.Fprintln(out, string(encoded))
+}
+
+// balanced returns true if all parentheses, brackets and braces outside of
+// string literals and comments are closed, i.e. the input is complete.
+func balanced(input string) bool {
+	depth := 0
+	inString := false
+	inComment := false
+	escaped := false
+
+	for _, r := range input {
+		switch {
+		case inComment:
+			inComment = r != '\n'
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+			// ignore
+		case r == '#':
+			inComment = true
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		}
+	}
+
+	return depth <= 0 && !inString
+}
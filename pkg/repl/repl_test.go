@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package repl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/test"
+	"go.xrstf.de/rudi-lda/pkg/test/emails"
+)
+
+func TestBalanced(t *testing.T) {
+	testcases := []struct {
+		input    string
+		expected bool
+	}{
+		{input: `.from.address`, expected: true},
+		{input: `(domain .from.address)`, expected: true},
+		{input: `(if (eq? .subject "foo")`, expected: false},
+		{input: "(if (eq? .subject \"foo\")\n  \"Lists\")", expected: true},
+		{input: `(matches? .subject "(")`, expected: true},
+		{input: `(matches? .subject "\"(")`, expected: true},
+		{input: `(matches? .subject "`, expected: false},
+		{input: "(domain # (\n  .from.address)", expected: true},
+	}
+
+	for _, testcase := range testcases {
+		if balanced(testcase.input) != testcase.expected {
+			t.Errorf("Expected balanced(%q) to be %v.", testcase.input, testcase.expected)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "history")
+
+	s := New(".").WithHistory(historyFile)
+	for _, input := range []string{"(domain .from.address)", "(if true\n  1)"} {
+		if err := s.remember(input); err != nil {
+			t.Fatalf("Failed to remember input: %v", err)
+		}
+	}
+
+	restored := New(".").WithHistory(historyFile)
+	if err := restored.loadHistory(); err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+
+	testcases := []struct {
+		input    string
+		expected string
+		invalid  bool
+	}{
+		{input: "(user .from.address)", expected: "(user .from.address)"},
+		{input: "!!", expected: "(if true\n  1)"},
+		{input: "!1", expected: "(domain .from.address)"},
+		{input: "!3", invalid: true},
+		{input: "!x", invalid: true},
+	}
+
+	for _, testcase := range testcases {
+		expanded, err := restored.expandHistory(testcase.input)
+		if testcase.invalid {
+			if err == nil {
+				t.Errorf("Expected %q to be invalid.", testcase.input)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Failed to expand %q: %v", testcase.input, err)
+		} else if expanded != testcase.expected {
+			t.Errorf("Expected %q to expand to %q, got %q.", testcase.input, testcase.expected, expanded)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	scriptFile, err := test.TempScript(`(score! 2.5 "foo") (score! 3 "bar")`)
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(scriptFile)
+
+	testcases := []struct {
+		input    string
+		expected string
+	}{
+		{input: `(domain .from.address)`, expected: `"github.com"`},
+		{input: `(score! 2 "foo")`, expected: `2`},
+		// scores do not carry over from previous inputs
+		{input: `(score! 1 "bar")`, expected: `1`},
+		{input: ":load " + scriptFile, expected: `5.5`},
+	}
+
+	s := New(t.TempDir())
+	s.messages = append(s.messages, message{name: "github", msg: emails.GitHubIssueClosed()})
+
+	for _, testcase := range testcases {
+		t.Run(testcase.input, func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := s.handle(context.Background(), testcase.input, &out); err != nil {
+				t.Fatalf("Failed to evaluate: %v", err)
+			}
+
+			if result := strings.TrimSpace(out.String()); result != testcase.expected {
+				t.Fatalf("Expected %s, got %s.", testcase.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"errors"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/bayes"
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// bayesFunctions returns the functions for the statistical spam classifier.
+// The message is only classified once per script run.
+func bayesFunctions(msg *email.Message) rudi.Functions {
+	f := &bayesFuncs{
+		msg: msg,
+	}
+
+	return rudi.Functions{
+		"bayes-score": rudi.NewFunctionBuilder(f.scoreFunc).WithDescription("returns the spam probability (0 to 1) according to the trained classifier; 0.5 if undecided").Build(),
+	}
+}
+
+type bayesFuncs struct {
+	msg    *email.Message
+	result *bayes.Result
+}
+
+func (f *bayesFuncs) scoreFunc() (any, error) {
+	if f.result == nil {
+		if dataDirectory == "" {
+			return nil, errors.New("no datadir configured")
+		}
+
+		db, err := bayes.Load(bayes.Filename(dataDirectory))
+		if err != nil {
+			return nil, err
+		}
+
+		result := db.Classify(bayes.Tokenize(f.msg))
+		f.result = &result
+	}
+
+	return f.result.Score, nil
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/dnsbl"
+)
+
+var dnsblChecker = dnsbl.NewChecker(dnsbl.NewResolver(""), "", time.Hour)
+
+// SetDNSBLChecker configures the checker used for all DNS blocklist lookups.
+func SetDNSBLChecker(checker *dnsbl.Checker) {
+	dnsblChecker = checker
+}
+
+// dnsFunctions returns the DNS blocklist functions. All functions returned
+// share a single session, so the query budget applies to the whole script
+// run. Lookup errors (including timeouts and an exceeded budget) are
+// treated as "not listed", so that a DNS outage cannot make e-mails
+// undeliverable.
+func dnsFunctions(ctx context.Context) rudi.Functions {
+	f := &dnsFuncs{
+		ctx:     ctx,
+		session: dnsblChecker.NewSession(),
+	}
+
+	return rudi.Functions{
+		"dnsbl":  rudi.NewFunctionBuilder(f.dnsblFunc).WithDescription("returns the listing code of the IP in the DNS blocklist zone (or null)").Build(),
+		"dnsbl?": rudi.NewFunctionBuilder(f.isDNSBLFunc).WithDescription("returns true if the IP is listed in the DNS blocklist zone").Build(),
+		"uribl":  rudi.NewFunctionBuilder(f.uriblFunc).WithDescription("returns the listing code of the first of the given domains that is listed in the URI blocklist zone (or null)").Build(),
+		"uribl?": rudi.NewFunctionBuilder(f.isURIBLFunc).WithDescription("returns true if any of the given domains is listed in the URI blocklist zone").Build(),
+	}
+}
+
+type dnsFuncs struct {
+	ctx     context.Context
+	session *dnsbl.Session
+}
+
+func (f *dnsFuncs) dnsblFunc(ip string, zone string) (any, error) {
+	if ip == "" {
+		return nil, nil
+	}
+
+	code, err := f.session.CheckIP(f.ctx, ip, zone)
+	if err != nil || code == "" {
+		return nil, nil
+	}
+
+	return code, nil
+}
+
+func (f *dnsFuncs) isDNSBLFunc(ip string, zone string) (any, error) {
+	code, err := f.dnsblFunc(ip, zone)
+	return code != nil, err
+}
+
+// uriblFunc accepts a single domain or a vector of domains.
+func (f *dnsFuncs) uriblFunc(domains any, zone string) (any, error) {
+	var candidates []any
+
+	switch d := domains.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		candidates = []any{d}
+	case []any:
+		candidates = d
+	default:
+		return nil, errors.New("expected a domain or a vector of domains")
+	}
+
+	for _, candidate := range candidates {
+		domain, ok := candidate.(string)
+		if !ok {
+			return nil, errors.New("expected a domain or a vector of domains")
+		}
+
+		if domain == "" {
+			continue
+		}
+
+		code, err := f.session.CheckDomain(f.ctx, domain, zone)
+		if err != nil {
+			continue
+		}
+
+		if code != "" {
+			return code, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *dnsFuncs) isURIBLFunc(domains any, zone string) (any, error) {
+	code, err := f.uriblFunc(domains, zone)
+	return code != nil, err
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"errors"
+	"path/filepath"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/lists"
+)
+
+var dataDirectory = ""
+
+// SetDataDirectory configures the datadir, in whose "lists" directory list
+// files are looked up if they are not found next to the script.
+func SetDataDirectory(dirname string) {
+	dataDirectory = dirname
+}
+
+// fileFunctions returns functions that load data from files. Relative
+// filenames are resolved relative to the script's directory.
+func fileFunctions(scriptDir string) rudi.Functions {
+	f := &fileFuncs{
+		scriptDir: scriptDir,
+	}
+
+	return rudi.Functions{
+		"regex-set-file": rudi.NewFunctionBuilder(f.regexSetFileFunc).WithDescription("loads newline-separated expressions from a file into a regex set").Build(),
+		"in-list?":       rudi.NewFunctionBuilder(f.inListFunc).WithDescription("returns true if the value (an address, domain or IP) matches any entry of the named list").Build(),
+		"list-match":     rudi.NewFunctionBuilder(f.listMatchFunc).WithDescription("returns the entry of the named list that matches the value (or null)").Build(),
+		"list-entries":   rudi.NewFunctionBuilder(f.listEntriesFunc).WithDescription("returns all entries of the named list").Build(),
+	}
+}
+
+type fileFuncs struct {
+	scriptDir string
+}
+
+func (f *fileFuncs) resolve(filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+
+	return filepath.Join(f.scriptDir, filename)
+}
+
+func (f *fileFuncs) regexSetFileFunc(filename string) (any, error) {
+	return LoadRegexSet(f.resolve(filename))
+}
+
+func (f *fileFuncs) loadList(name string) (*lists.List, error) {
+	var directories []string
+
+	if f.scriptDir != "" {
+		directories = append(directories, f.scriptDir)
+	}
+
+	if dataDirectory != "" {
+		directories = append(directories, lists.Directory(dataDirectory))
+	}
+
+	filename, err := lists.Find(name, directories...)
+	if err != nil {
+		return nil, err
+	}
+
+	return lists.Load(filename)
+}
+
+func (f *fileFuncs) inListFunc(name string, value any) (any, error) {
+	entry, err := f.listMatchFunc(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry != nil, nil
+}
+
+func (f *fileFuncs) listMatchFunc(name string, value any) (any, error) {
+	if thing, ok := value.(map[string]any); ok {
+		value = thing["address"]
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		if value == nil {
+			return nil, nil
+		}
+
+		return nil, errors.New("cannot deal with provided value")
+	}
+
+	list, err := f.loadList(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := list.Match(s)
+	if !ok {
+		return nil, nil
+	}
+
+	return entry, nil
+}
+
+func (f *fileFuncs) listEntriesFunc(name string) (any, error) {
+	list, err := f.loadList(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return stringsToAnys(list.Entries()), nil
+}
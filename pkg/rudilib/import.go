@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// LibraryExtension is the optional extension of library files.
+const LibraryExtension = ".rudi"
+
+// LibraryDirectory returns the directory inside the datadir in which
+// libraries are looked up if they are not found next to the script.
+func LibraryDirectory(datadir string) string {
+	return filepath.Join(datadir, "library")
+}
+
+// FindLibrary returns the filename for the given library name. The name is
+// looked up relative to the importing script first and then in the
+// datadir, with and without the LibraryExtension.
+func FindLibrary(name string, scriptDir string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+
+	directories := []string{scriptDir}
+	if dataDirectory != "" {
+		directories = append(directories, LibraryDirectory(dataDirectory))
+	}
+
+	for _, dir := range directories {
+		for _, candidate := range []string{name, name + LibraryExtension} {
+			filename := filepath.Join(dir, candidate)
+
+			if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+				return filename, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("library %q not found", name)
+}
+
+type cachedLibrary struct {
+	modified int64
+	program  rudi.Program
+}
+
+var (
+	librariesLock sync.Mutex
+	libraries     = map[string]cachedLibrary{}
+)
+
+// loadLibrary parses a library file. Libraries are cached until the file
+// is modified, so they are only parsed once per process.
+func loadLibrary(filename string) (rudi.Program, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	modified := info.ModTime().UnixNano()
+
+	librariesLock.Lock()
+	cached, ok := libraries[filename]
+	librariesLock.Unlock()
+
+	if ok && cached.modified == modified {
+		return cached.program, nil
+	}
+
+	program, err := loadProgram(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	librariesLock.Lock()
+	libraries[filename] = cachedLibrary{
+		modified: modified,
+		program:  program,
+	}
+	librariesLock.Unlock()
+
+	return program, nil
+}
+
+// importer evaluates the libraries imported by a script (and by the
+// libraries themselves). Every library is evaluated only once per run,
+// against the same message as the script.
+type importer struct {
+	msg        *email.Message
+	extraData  map[string]any
+	extraVars  rudi.Variables
+	extraFuncs rudi.Functions
+
+	// stack contains the files that are currently being evaluated, the
+	// script first, to detect import cycles.
+	stack   []string
+	results map[string]any
+}
+
+type importerKey struct{}
+
+// withImporter returns a context for running the given script, unless the
+// script is itself a library that is being imported.
+func withImporter(ctx context.Context, scriptFile string, msg *email.Message, extraData map[string]any, extraVars rudi.Variables, extraFuncs rudi.Functions) context.Context {
+	if getImporter(ctx) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, importerKey{}, &importer{
+		msg:        msg,
+		extraData:  extraData,
+		extraVars:  extraVars,
+		extraFuncs: extraFuncs,
+		stack:      []string{absolute(scriptFile)},
+		results:    map[string]any{},
+	})
+}
+
+func getImporter(ctx context.Context) *importer {
+	i, _ := ctx.Value(importerKey{}).(*importer)
+	return i
+}
+
+func (i *importer) load(ctx context.Context, filename string) (any, error) {
+	filename = absolute(filename)
+
+	if result, ok := i.results[filename]; ok {
+		return result, nil
+	}
+
+	for idx, file := range i.stack {
+		if file == filename {
+			return nil, fmt.Errorf("import cycle: %s", chain(append(i.stack[idx:], filename)))
+		}
+	}
+
+	program, err := loadLibrary(filename)
+	if err != nil {
+		return nil, fmt.Errorf("invalid library: %w", err)
+	}
+
+	var result any
+
+	// empty libraries are valid, but useless
+	if program != nil {
+		i.stack = append(i.stack, filename)
+		result, err = run(ctx, program, filename, i.msg, i.extraData, i.extraVars, i.extraFuncs)
+		i.stack = i.stack[:len(i.stack)-1]
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	i.results[filename] = result
+
+	return result, nil
+}
+
+// importFunctions returns the functions to import libraries into the given
+// script.
+func importFunctions(ctx context.Context, scriptFile string) rudi.Functions {
+	f := &importFuncs{
+		ctx:        ctx,
+		scriptFile: scriptFile,
+	}
+
+	return rudi.Functions{
+		"import": rudi.NewFunctionBuilder(f.importFunc, f.importKeyFunc).WithDescription("evaluates a library file once and returns its result (or the value of the given key of its result)").Build(),
+	}
+}
+
+type importFuncs struct {
+	ctx        context.Context
+	scriptFile string
+}
+
+func (f *importFuncs) importFunc(name string) (any, error) {
+	i := getImporter(f.ctx)
+	if i == nil {
+		return nil, errors.New("libraries cannot be imported here")
+	}
+
+	result, err := f.importLibrary(i, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to import %q: %w", position(f.scriptFile, name), name, err)
+	}
+
+	return result, nil
+}
+
+func (f *importFuncs) importLibrary(i *importer, name string) (any, error) {
+	filename, err := FindLibrary(name, filepath.Dir(f.scriptFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return i.load(f.ctx, filename)
+}
+
+func (f *importFuncs) importKeyFunc(name string, key string) (any, error) {
+	result, err := f.importFunc(name)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, ok := result.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: library %q does not return an object", position(f.scriptFile, name), name)
+	}
+
+	value, ok := definitions[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: library %q does not define %q", position(f.scriptFile, name), name, key)
+	}
+
+	return value, nil
+}
+
+func absolute(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		return abs
+	}
+
+	return filename
+}
+
+func chain(files []string) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+
+	return strings.Join(names, " -> ")
+}
+
+// position returns "file:line" for the first import of the given library
+// in the script. Rudi does not keep source positions after parsing, so the
+// script is searched for the import instead.
+func position(scriptFile string, name string) string {
+	display := filepath.Base(scriptFile)
+
+	f, err := os.Open(scriptFile)
+	if err != nil {
+		return display
+	}
+	defer f.Close()
+
+	expr := regexp.MustCompile(`\(import\s+"` + regexp.QuoteMeta(name) + `"`)
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if expr.MatchString(scanner.Text()) {
+			return fmt.Sprintf("%s:%d", display, line)
+		}
+	}
+
+	return display
+}
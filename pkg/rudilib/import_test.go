@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/test/emails"
+)
+
+func TestImport(t *testing.T) {
+	testcases := []struct {
+		name      string
+		files     map[string]string
+		script    string
+		expected  any
+		errorPart string
+	}{
+		{
+			name: "import a predicate",
+			files: map[string]string{
+				"lib/github.rudi": `(matches? .from.address "@github\\.com$")`,
+			},
+			script:   `(import "lib/github.rudi")`,
+			expected: true,
+		},
+		{
+			name: "extension is optional",
+			files: map[string]string{
+				"github.rudi": `(matches? .from.address "@github\\.com$")`,
+			},
+			script:   `(import "github")`,
+			expected: true,
+		},
+		{
+			name: "import a named definition",
+			files: map[string]string{
+				"common.rudi": `{"is-github" (matches? .from.address "@github\\.com$") "answer" 42}`,
+			},
+			script:   `(import "common" "answer")`,
+			expected: int64(42),
+		},
+		{
+			name: "nested imports are relative to the library",
+			files: map[string]string{
+				"lib/outer.rudi": `(import "inner")`,
+				"lib/inner.rudi": `"inner"`,
+			},
+			script:   `(import "lib/outer")`,
+			expected: "inner",
+		},
+		{
+			name: "importing twice is fine",
+			files: map[string]string{
+				"a.rudi": `(import "b")`,
+				"b.rudi": `1`,
+			},
+			script:   `(+ (import "a") (import "b"))`,
+			expected: int64(2),
+		},
+		{
+			name: "missing library",
+			script: `true
+(import "missing")`,
+			errorPart: `test.rudi:2: failed to import "missing"`,
+		},
+		{
+			name: "unknown definition",
+			files: map[string]string{
+				"common.rudi": `{"answer" 42}`,
+			},
+			script:    `(import "common" "question")`,
+			errorPart: `does not define "question"`,
+		},
+		{
+			name: "import cycle",
+			files: map[string]string{
+				"a.rudi": `(import "b")`,
+				"b.rudi": `(import "a")`,
+			},
+			script:    `(import "a")`,
+			errorPart: "import cycle: a.rudi -> b.rudi -> a.rudi",
+		},
+		{
+			name: "importing the script itself",
+			files: map[string]string{
+				"a.rudi": `(import "test")`,
+			},
+			script:    `(import "a")`,
+			errorPart: "import cycle: test.rudi -> a.rudi -> test.rudi",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			if testcase.files == nil {
+				testcase.files = map[string]string{}
+			}
+
+			testcase.files["test.rudi"] = testcase.script
+
+			for name, content := range testcase.files {
+				filename := filepath.Join(dir, name)
+
+				if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := ProcessMessage(context.Background(), filepath.Join(dir, "test.rudi"), emails.GitHubIssueClosed(), nil, nil, nil)
+
+			if testcase.errorPart != "" {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v.", result)
+				}
+
+				if !strings.Contains(err.Error(), testcase.errorPart) {
+					t.Fatalf("Expected error to contain %q, got %v.", testcase.errorPart, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to run script: %v", err)
+			}
+
+			if result != testcase.expected {
+				t.Fatalf("Expected %v, got %v.", testcase.expected, result)
+			}
+		})
+	}
+}
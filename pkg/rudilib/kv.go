@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"fmt"
+	"time"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/kv"
+)
+
+var kvStore = kv.New("")
+
+// SetKVStore configures the store used by the key-value functions. By
+// default, values are only kept in memory.
+func SetKVStore(store *kv.Store) {
+	kvStore = store
+}
+
+// KVFunctions give scripts access to state that persists across deliveries.
+// TTLs and windows are durations like "1h" or "720h".
+var KVFunctions = rudi.Functions{
+	"kv-get":        rudi.NewFunctionBuilder(kvGetFunc).WithDescription("returns the stored value for the key (or null)").Build(),
+	"kv-set!":       rudi.NewFunctionBuilder(kvSetFunc, kvSetTTLFunc).WithDescription("stores a value for the key, optionally only for the given TTL, and returns the value").Build(),
+	"counter-incr!": rudi.NewFunctionBuilder(counterIncrFunc, counterIncrWindowFunc).WithDescription("increments the counter with the key and returns its new value; counters start over once the optional window has passed").Build(),
+	"seen-before?":  rudi.NewFunctionBuilder(seenBeforeFunc, seenBeforeTTLFunc).WithDescription("remembers the key (optionally only for the given TTL) and returns true if it was already known").Build(),
+}
+
+func kvGetFunc(key string) (any, error) {
+	entry, err := kvStore.Get(key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	return entry.Value, nil
+}
+
+func kvSetFunc(key string, value any) (any, error) {
+	return kvSetTTLFunc(key, value, "")
+}
+
+func kvSetTTLFunc(key string, value any, ttl string) (any, error) {
+	duration, err := parseTTL(ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := kvStore.Set(key, value, duration); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func counterIncrFunc(key string) (any, error) {
+	return counterIncrWindowFunc(key, "")
+}
+
+func counterIncrWindowFunc(key string, window string) (any, error) {
+	duration, err := parseTTL(window)
+	if err != nil {
+		return nil, err
+	}
+
+	return kvStore.Increment(key, duration)
+}
+
+func seenBeforeFunc(key string) (any, error) {
+	return seenBeforeTTLFunc(key, "")
+}
+
+func seenBeforeTTLFunc(key string, ttl string) (any, error) {
+	duration, err := parseTTL(ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return kvStore.SeenBefore(key, duration)
+}
+
+func parseTTL(ttl string) (time.Duration, error) {
+	if ttl == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", ttl, err)
+	}
+
+	return duration, nil
+}
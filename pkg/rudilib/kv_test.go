@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/kv"
+	"go.xrstf.de/rudi-lda/pkg/test/emails"
+)
+
+func TestKVFunctions(t *testing.T) {
+	dir := t.TempDir()
+	defer SetKVStore(kv.New(""))
+
+	SetKVStore(kv.New(kv.Filename(dir)))
+
+	testcases := []struct {
+		name     string
+		script   string
+		expected []any
+	}{
+		{
+			name:     "values",
+			script:   `(kv-set! "answer" 42) (kv-get "answer")`,
+			expected: []any{int64(42), int64(42)},
+		},
+		{
+			name:     "unknown key",
+			script:   `(kv-get "unknown")`,
+			expected: []any{nil},
+		},
+		{
+			name:     "counters",
+			script:   `(counter-incr! "counter" "1h")`,
+			expected: []any{int64(1), int64(2), int64(3)},
+		},
+		{
+			name:     "seen before",
+			script:   `(seen-before? .from.address)`,
+			expected: []any{false, true},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			scriptFile := filepath.Join(t.TempDir(), "test.rudi")
+			if err := os.WriteFile(scriptFile, []byte(testcase.script), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, expected := range testcase.expected {
+				result, err := ProcessMessage(context.Background(), scriptFile, emails.GitHubIssueClosed(), nil, nil, nil)
+				if err != nil {
+					t.Fatalf("Failed to run script: %v", err)
+				}
+
+				if result != expected {
+					t.Fatalf("Expected %v, got %v.", expected, result)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.xrstf.de/rudi"
+	"go.xrstf.de/rudi/pkg/lang/ast"
+)
+
+// ErrLimitExceeded is returned when a script is aborted because it
+// exceeded one of its Limits.
+var ErrLimitExceeded = errors.New("script exceeded its limits")
+
+// Limits restrict what scripts can do.
+type Limits struct {
+	// Timeout is the maximum runtime of a single script. Zero disables the
+	// timeout.
+	Timeout time.Duration
+	// MaxSteps is the maximum number of function calls (including
+	// built-in functions like `if`) of a single script. Zero disables the
+	// limit.
+	MaxSteps int
+	// MaxDepth is the maximum nesting depth of function calls. Zero
+	// disables the limit.
+	MaxDepth int
+	// UnsafeFunctions are the names of Rudi's unsafe built-in functions
+	// (those that modify variables or the document) that scripts may use.
+	// "*" allows all of them. By default, only safe functions are allowed.
+	UnsafeFunctions []string
+}
+
+var DefaultLimits = Limits{
+	Timeout:  10 * time.Second,
+	MaxSteps: 100000,
+	MaxDepth: 100,
+}
+
+var limits = DefaultLimits
+
+// SetLimits configures the limits for all scripts.
+func SetLimits(l Limits) {
+	limits = l
+}
+
+func (l Limits) unsafeFunctions() rudi.Functions {
+	funcs := rudi.Functions{}
+
+	for name, fn := range rudi.NewUnsafeBuiltInFunctions() {
+		for _, allowed := range l.UnsafeFunctions {
+			if allowed == "*" || allowed == name {
+				funcs[name] = fn
+				break
+			}
+		}
+	}
+
+	return funcs
+}
+
+// limiter counts all function calls of a single script run and aborts it
+// once it exceeds the limits or its context is cancelled.
+type limiter struct {
+	ctx    context.Context
+	limits Limits
+	steps  int
+	depth  int
+	// err is the first violation; Rudi might not wrap the errors returned
+	// by functions, so it is remembered here.
+	err error
+}
+
+func newLimiter(ctx context.Context, limits Limits) *limiter {
+	return &limiter{
+		ctx:    ctx,
+		limits: limits,
+	}
+}
+
+type limiterKey struct{}
+
+func withLimiter(ctx context.Context, l *limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+func getLimiter(ctx context.Context) *limiter {
+	l, _ := ctx.Value(limiterKey{}).(*limiter)
+	return l
+}
+
+func (l *limiter) wrap(funcs rudi.Functions) rudi.Functions {
+	wrapped := rudi.Functions{}
+	for name, fn := range funcs {
+		wrapped[name] = &limitedFunction{
+			Function: fn,
+			limiter:  l,
+		}
+	}
+
+	return wrapped
+}
+
+func (l *limiter) enter() error {
+	l.steps++
+	l.depth++
+
+	if l.err != nil {
+		return l.err
+	}
+
+	switch {
+	case l.limits.MaxSteps > 0 && l.steps > l.limits.MaxSteps:
+		l.err = fmt.Errorf("%w: more than %d steps", ErrLimitExceeded, l.limits.MaxSteps)
+	case l.limits.MaxDepth > 0 && l.depth > l.limits.MaxDepth:
+		l.err = fmt.Errorf("%w: nested deeper than %d calls", ErrLimitExceeded, l.limits.MaxDepth)
+	case errors.Is(l.ctx.Err(), context.DeadlineExceeded):
+		l.err = fmt.Errorf("%w: ran longer than %v", ErrLimitExceeded, l.limits.Timeout)
+	case l.ctx.Err() != nil:
+		l.err = l.ctx.Err()
+	}
+
+	return l.err
+}
+
+func (l *limiter) leave() {
+	l.depth--
+}
+
+type limitedFunction struct {
+	rudi.Function
+
+	limiter *limiter
+}
+
+func (f *limitedFunction) Evaluate(ctx rudi.Context, args []ast.Expression) (any, error) {
+	defer f.limiter.leave()
+
+	if err := f.limiter.enter(); err != nil {
+		return nil, err
+	}
+
+	return f.Function.Evaluate(ctx, args)
+}
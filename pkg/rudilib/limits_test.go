@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/test/emails"
+)
+
+func TestLimits(t *testing.T) {
+	defer SetLimits(DefaultLimits)
+
+	testcases := []struct {
+		name     string
+		script   string
+		limits   Limits
+		expected any
+		exceeded bool
+		invalid  bool
+	}{
+		{
+			name:     "within limits",
+			script:   `(+ 1 (+ 1 (+ 1 1)))`,
+			limits:   Limits{MaxSteps: 3, MaxDepth: 3},
+			expected: int64(4),
+		},
+		{
+			name:     "too many steps",
+			script:   `(+ 1 (+ 1 (+ 1 1)))`,
+			limits:   Limits{MaxSteps: 2},
+			exceeded: true,
+		},
+		{
+			name:     "nested too deep",
+			script:   `(+ 1 (+ 1 (+ 1 1)))`,
+			limits:   Limits{MaxDepth: 2},
+			exceeded: true,
+		},
+		{
+			name:    "unsafe functions are not available by default",
+			script:  `(set $foo 1)`,
+			limits:  DefaultLimits,
+			invalid: true,
+		},
+		{
+			name:     "allowed unsafe function",
+			script:   `(set $foo 1)`,
+			limits:   Limits{UnsafeFunctions: []string{"set"}},
+			expected: int64(1),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			scriptFile := filepath.Join(t.TempDir(), "test.rudi")
+			if err := os.WriteFile(scriptFile, []byte(testcase.script), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			SetLimits(testcase.limits)
+
+			result, err := ProcessMessage(context.Background(), scriptFile, emails.GitHubIssueClosed(), nil, nil, nil)
+
+			switch {
+			case testcase.exceeded:
+				if !errors.Is(err, ErrLimitExceeded) {
+					t.Fatalf("Expected limit to be exceeded, got %v.", err)
+				}
+
+			case testcase.invalid:
+				if err == nil {
+					t.Fatalf("Expected an error, got %v.", result)
+				}
+
+			case err != nil:
+				t.Fatalf("Failed to run script: %v", err)
+
+			case result != testcase.expected:
+				t.Fatalf("Expected %v (%T), got %v (%T).", testcase.expected, testcase.expected, result, result)
+			}
+		})
+	}
+}
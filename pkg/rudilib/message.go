@@ -18,7 +18,9 @@ import (
 	"go.xrstf.de/rudi-lda/pkg/email"
 )
 
-func ProcessMessage(ctx context.Context, scriptFile string, msg *email.Message, extraVars rudi.Variables, extraFuncs rudi.Functions) (result any, err error) {
+// ProcessMessage runs a script against the given message. The extraData is
+// merged into the top level of the document, next to the message fields.
+func ProcessMessage(ctx context.Context, scriptFile string, msg *email.Message, extraData map[string]any, extraVars rudi.Variables, extraFuncs rudi.Functions) (result any, err error) {
 	program, err := loadProgram(scriptFile)
 	if err != nil {
 		return nil, fmt.Errorf("invalid script: %w", err)
@@ -28,11 +30,48 @@ func ProcessMessage(ctx context.Context, scriptFile string, msg *email.Message,
 		return nil, nil
 	}
 
+	return run(ctx, program, scriptFile, msg, extraData, extraVars, extraFuncs)
+}
+
+// Evaluate runs code against the given message, just like ProcessMessage
+// does for script files. Files used by the code are looked up in dir.
+func Evaluate(ctx context.Context, code string, dir string, msg *email.Message, extraData map[string]any, extraVars rudi.Variables, extraFuncs rudi.Functions) (any, error) {
+	program, err := rudi.Parse("repl", code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid code: %w", err)
+	}
+
+	return run(ctx, program, filepath.Join(dir, "repl"), msg, extraData, extraVars, extraFuncs)
+}
+
+func run(ctx context.Context, program rudi.Program, scriptFile string, msg *email.Message, extraData map[string]any, extraVars rudi.Variables, extraFuncs rudi.Functions) (result any, err error) {
 	data, err := msg.ToJSON()
 	if err != nil {
 		return nil, fmt.Errorf("cannot turn e-mail into raw data: %w", err)
 	}
 
+	if document, ok := data.(map[string]any); ok {
+		for key, value := range extraData {
+			document[key] = value
+		}
+	}
+
+	// imported libraries share the limits of the script that imports them
+	limiter := getLimiter(ctx)
+	if limiter == nil {
+		if limits.Timeout > 0 {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+			defer cancel()
+		}
+
+		limiter = newLimiter(ctx, limits)
+		ctx = withLimiter(ctx, limiter)
+	}
+
+	ctx = withImporter(ctx, scriptFile, msg, extraData, extraVars, extraFuncs)
+
 	defer func() {
 		if p := recover(); p != nil {
 			err = fmt.Errorf("Rudi panicked: %v: %s", err, debug.Stack())
@@ -44,9 +83,13 @@ func ProcessMessage(ctx context.Context, scriptFile string, msg *email.Message,
 		ctx,
 		data,
 		rudi.NewVariables().SetMany(extraVars),
-		getFunctions().Add(extraFuncs),
+		limiter.wrap(AllFunctions(ctx, scriptFile, msg, extraFuncs)),
 		coalescing.NewStrict(),
 	)
+	if limiter.err != nil {
+		return nil, limiter.err
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("script failed: %w", err)
 	}
@@ -72,12 +115,29 @@ func loadProgram(scriptFile string) (rudi.Program, error) {
 	return rudi.Parse(filename, code)
 }
 
-func getFunctions() rudi.Functions {
-	funcs := rudi.
-		NewSafeBuiltInFunctions().
-		Add(rudi.NewUnsafeBuiltInFunctions()).
+// AllFunctions returns all functions available to the given script file:
+// Rudi's safe built-in functions (plus the unsafe ones allowed by the
+// Limits), the functions provided by Rudi-LDA and the extraFuncs.
+func AllFunctions(ctx context.Context, scriptFile string, msg *email.Message, extraFuncs rudi.Functions) rudi.Functions {
+	funcs := rudi.Functions{}.
 		Add(Functions).
-		Add(set.Functions)
+		Add(TextFunctions).
+		Add(URLFunctions).
+		Add(SpoofingFunctions).
+		Add(KVFunctions).
+		Add(fileFunctions(filepath.Dir(scriptFile))).
+		Add(importFunctions(ctx, scriptFile)).
+		Add(dnsFunctions(ctx)).
+		Add(bayesFunctions(msg)).
+		Add(set.Functions).
+		Add(extraFuncs)
+
+	if tracer := getTracer(ctx); tracer != nil {
+		funcs = tracer.wrap(filepath.Base(scriptFile), funcs)
+	}
 
-	return funcs
+	return rudi.
+		NewSafeBuiltInFunctions().
+		Add(limits.unsafeFunctions()).
+		Add(funcs)
 }
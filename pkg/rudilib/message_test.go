@@ -6,11 +6,15 @@ package rudilib
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"go.xrstf.de/rudi"
 
+	"go.xrstf.de/rudi-lda/pkg/dnsbl"
+	"go.xrstf.de/rudi-lda/pkg/lists"
 	"go.xrstf.de/rudi-lda/pkg/test"
 	"go.xrstf.de/rudi-lda/pkg/test/emails"
 )
@@ -21,6 +25,7 @@ func TestProcessMessage(t *testing.T) {
 
 	testcases := []struct {
 		script     string
+		extraData  map[string]any
 		extraVars  rudi.Variables
 		extraFuncs rudi.Functions
 		expected   any
@@ -94,6 +99,208 @@ func TestProcessMessage(t *testing.T) {
 			script:   `(header "Received")`,
 			expected: `from [192.30.252.201] (out-18.smtp.github.com) by mailserver.example.com (chasquid) with ESMTPS tls TLS_AES_128_GCM_SHA256 (over SMTP, TLS-1.3, envelope from "noreply@github.com") ; Sat, 17 Feb 2024 15:20:18 +0000`,
 		},
+		{
+			script:   `(match .subject "Issue #([0-9]+)")`,
+			expected: []any{"Issue #868", "868"},
+		},
+		{
+			script:   `(match .subject "does not exist")`,
+			expected: nil,
+		},
+		{
+			script:   `(match-all "a1 b2" "([a-z])([0-9])")`,
+			expected: []any{[]any{"a1", "a", "1"}, []any{"b2", "b", "2"}},
+		},
+		{
+			script:   `(match-named .subject "Issue #(?P<id>[0-9]+)")`,
+			expected: map[string]any{"id": "868"},
+		},
+		{
+			script:   `(regex-replace "JIRA 123" "([A-Z]+) ([0-9]+)" "Tickets.$1-$2")`,
+			expected: "Tickets.JIRA-123",
+		},
+		{
+			script:   `(casefold "Straße")`,
+			expected: "strasse",
+		},
+		{
+			script:   `(nfkc "ｐａｙｐａｌ")`,
+			expected: "paypal",
+		},
+		{
+			script:   `(collapse-whitespace "  foo   bar ")`,
+			expected: "foo bar",
+		},
+		{
+			script:   `(registrable-domain "https://www.example.co.uk/path")`,
+			expected: "example.co.uk",
+		},
+		{
+			script:   `(registrable-domain .from)`,
+			expected: "github.com",
+		},
+		{
+			script:   `(ip-host? "http://3232235777/track")`,
+			expected: true,
+		},
+		{
+			script:   `(shortener? "https://bit.ly/3xyz")`,
+			expected: true,
+		},
+		{
+			script:   `(url-domains ["https://a.example.com" "http://b.example.com/x" "https://example.org"])`,
+			expected: []any{"example.com", "example.org"},
+		},
+		{
+			script:   `(confusable? "pаypаl.com" "paypal.com")`,
+			expected: true,
+		},
+		{
+			script:   `(similar-domain "paypa1.com" ["paypal.com" "github.com"])`,
+			expected: "paypal.com",
+		},
+		{
+			script:   `(similar-domain? (domain .from) ["paypal.com" "github.com"])`,
+			expected: false,
+		},
+		{
+			script:   `(display-name-mismatch? .from)`,
+			expected: false,
+		},
+		{
+			script:   `(display-name-mismatch .from ["SomeGithubUser"])`,
+			expected: "SomeGithubUser",
+		},
+		{
+			script: `.thread.folder`,
+			extraData: map[string]any{
+				"thread": map[string]any{"folder": "Projects"},
+			},
+			expected: "Projects",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.script, func(t *testing.T) {
+			scriptFile, err := test.TempScript(testcase.script)
+			if err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+			defer os.Remove(scriptFile)
+
+			result, err := ProcessMessage(ctx, scriptFile, msg, testcase.extraData, testcase.extraVars, testcase.extraFuncs)
+			if err != nil {
+				t.Fatalf("Failed to process: %v", err)
+			}
+
+			if !cmp.Equal(testcase.expected, result) {
+				t.Fatalf("Expected %+v, got %+v", testcase.expected, result)
+			}
+		})
+	}
+}
+
+func TestListFunctions(t *testing.T) {
+	ctx := context.Background()
+	msg := emails.GitHubIssueClosed()
+
+	datadir := t.TempDir()
+	SetDataDirectory(datadir)
+	defer SetDataDirectory("")
+
+	listDir := lists.Directory(datadir)
+	if err := os.MkdirAll(listDir, 0755); err != nil {
+		t.Fatalf("Failed to create list directory: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(listDir, "blocked-domains.txt"), []byte("# comment\ngithub.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to write list: %v", err)
+	}
+
+	testcases := []struct {
+		script   string
+		expected any
+	}{
+		{
+			script:   `(in-list? "blocked-domains" (domain .from))`,
+			expected: true,
+		},
+		{
+			script:   `(in-list? "blocked-domains" .from)`,
+			expected: true,
+		},
+		{
+			script:   `(in-list? "blocked-domains" "example.com")`,
+			expected: false,
+		},
+		{
+			script:   `(list-match "blocked-domains" "notifications@noreply.github.com")`,
+			expected: "github.com",
+		},
+		{
+			script:   `(list-entries "blocked-domains")`,
+			expected: []any{"github.com"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.script, func(t *testing.T) {
+			scriptFile, err := test.TempScript(testcase.script)
+			if err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+			defer os.Remove(scriptFile)
+
+			result, err := ProcessMessage(ctx, scriptFile, msg, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to process: %v", err)
+			}
+
+			if !cmp.Equal(testcase.expected, result) {
+				t.Fatalf("Expected %+v, got %+v", testcase.expected, result)
+			}
+		})
+	}
+}
+
+func TestDNSFunctions(t *testing.T) {
+	ctx := context.Background()
+	msg := emails.GitHubIssueClosed()
+
+	server := test.NewDNSServer(t, map[string][]string{
+		"201.252.30.192.zen.example.org":   {"127.0.0.2"},
+		"spam.example.com.dbl.example.org": {"127.0.1.2"},
+	})
+
+	previous := dnsblChecker
+	defer SetDNSBLChecker(previous)
+
+	SetDNSBLChecker(dnsbl.NewChecker(dnsbl.NewResolver(server.Addr), "", time.Hour))
+
+	testcases := []struct {
+		script   string
+		expected any
+	}{
+		{
+			script:   `.relayIP`,
+			expected: "192.30.252.201",
+		},
+		{
+			script:   `(dnsbl .relayIP "zen.example.org")`,
+			expected: "127.0.0.2",
+		},
+		{
+			script:   `(dnsbl? .relayIP "other.example.org")`,
+			expected: false,
+		},
+		{
+			script:   `(uribl ["example.com" "spam.example.com"] "dbl.example.org")`,
+			expected: "127.0.1.2",
+		},
+		{
+			script:   `(uribl? (domain .from) "dbl.example.org")`,
+			expected: false,
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -104,7 +311,7 @@ func TestProcessMessage(t *testing.T) {
 			}
 			defer os.Remove(scriptFile)
 
-			result, err := ProcessMessage(ctx, scriptFile, msg, testcase.extraVars, testcase.extraFuncs)
+			result, err := ProcessMessage(ctx, scriptFile, msg, nil, nil, nil)
 			if err != nil {
 				t.Fatalf("Failed to process: %v", err)
 			}
@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	maxCachedRegexes    = 4096
+	maxCachedRegexSets  = 64
+	maxCachedRegexFiles = 64
+)
+
+var (
+	regexes    = newLRU[*regexp.Regexp](maxCachedRegexes)
+	regexSets  = newLRU[*RegexSet](maxCachedRegexSets)
+	regexFiles = newLRU[*regexFile](maxCachedRegexFiles)
+)
+
+// compileRegex compiles an expression, using a process-wide cache.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	return regexes.getOrCreate(pattern, func() (*regexp.Regexp, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %w", pattern, err)
+		}
+
+		return re, nil
+	})
+}
+
+// RegexSet is a precompiled list of expressions that can be matched against
+// in a single pass.
+type RegexSet struct {
+	patterns []string
+	// combined rejects non-matching inputs in a single pass
+	combined *regexp.Regexp
+	// regexes are the individual expressions, in the order of the patterns
+	regexes []*regexp.Regexp
+}
+
+// NewRegexSet compiles the given patterns into a single expression. Identical
+// pattern lists share the same RegexSet.
+func NewRegexSet(patterns []string) (*RegexSet, error) {
+	key := strings.Join(patterns, "\x00")
+
+	return regexSets.getOrCreate(key, func() (*RegexSet, error) {
+		return newRegexSet(patterns)
+	})
+}
+
+func newRegexSet(patterns []string) (*RegexSet, error) {
+	set := &RegexSet{
+		patterns: patterns,
+		regexes:  make([]*regexp.Regexp, len(patterns)),
+	}
+
+	if len(patterns) == 0 {
+		return set, nil
+	}
+
+	alternatives := make([]string, len(patterns))
+
+	for i, pattern := range patterns {
+		re, err := compileRegex(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		alternatives[i] = "(?:" + pattern + ")"
+		set.regexes[i] = re
+	}
+
+	combined, err := regexp.Compile(strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to combine expressions: %w", err)
+	}
+
+	set.combined = combined
+
+	return set, nil
+}
+
+// Match returns the first pattern (in the order the set was created with)
+// that matches the input. Inputs that match none of the patterns are
+// rejected in a single pass; for all others, the patterns are tried one by
+// one, because the combined expression would report the pattern that
+// matches earliest in the input instead.
+func (s *RegexSet) Match(input string) (string, bool) {
+	if s.combined == nil || !s.combined.MatchString(input) {
+		return "", false
+	}
+
+	for i, re := range s.regexes {
+		if re.MatchString(input) {
+			return s.patterns[i], true
+		}
+	}
+
+	return "", false
+}
+
+func (s *RegexSet) Len() int {
+	return len(s.patterns)
+}
+
+type regexFile struct {
+	modified int64
+	set      *RegexSet
+}
+
+// LoadRegexSet reads newline-separated expressions from a file. Empty lines
+// and lines starting with "#" are ignored. The set is cached until the file
+// is modified.
+func LoadRegexSet(filename string) (*RegexSet, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached, ok := regexFiles.get(filename); ok && cached.modified == info.ModTime().UnixNano() {
+		return cached.set, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	set, err := NewRegexSet(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression in %s: %w", filename, err)
+	}
+
+	regexFiles.set(filename, &regexFile{
+		modified: info.ModTime().UnixNano(),
+		set:      set,
+	})
+
+	return set, nil
+}
+
+// lru is a small, size-bounded least-recently-used cache.
+type lru[T any] struct {
+	lock    sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry[T any] struct {
+	key   string
+	value T
+}
+
+func newLRU[T any](size int) *lru[T] {
+	return &lru[T]{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (c *lru[T]) get(key string) (T, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruEntry[T]).value, true
+	}
+
+	var empty T
+	return empty, false
+}
+
+func (c *lru[T]) set(key string, value T) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry[T]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[T]{key: key, value: value})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[T]).key)
+	}
+}
+
+func (c *lru[T]) getOrCreate(key string, create func() (T, error)) (T, error) {
+	if value, ok := c.get(key); ok {
+		return value, nil
+	}
+
+	value, err := create()
+	if err != nil {
+		return value, err
+	}
+
+	c.set(key, value)
+
+	return value, nil
+}
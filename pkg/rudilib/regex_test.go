@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"testing"
+)
+
+func TestRegexSet(t *testing.T) {
+	set, err := NewRegexSet([]string{`^(foo|bar)@example\.com$`, `(?i)@SPAM\.`, `@(spam)\.example\.com$`})
+	if err != nil {
+		t.Fatalf("Failed to create regex set: %v", err)
+	}
+
+	testcases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "nobody@example.com", expected: ""},
+		{input: "bar@example.com", expected: `^(foo|bar)@example\.com$`},
+		{input: "someone@spam.example.com", expected: `(?i)@SPAM\.`},
+		{input: "someone@SPAM.example.com", expected: `(?i)@SPAM\.`},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.input, func(t *testing.T) {
+			matched, ok := set.Match(testcase.input)
+			if ok != (testcase.expected != "") {
+				t.Fatalf("Expected match=%v, got %v.", testcase.expected != "", ok)
+			}
+
+			if matched != testcase.expected {
+				t.Fatalf("Expected %q to match, got %q.", testcase.expected, matched)
+			}
+		})
+	}
+}
+
+func TestRegexSetOrder(t *testing.T) {
+	// the second pattern matches earlier in the input, but the first one
+	// was declared first
+	set, err := NewRegexSet([]string{`example\.com$`, `^newsletter@`})
+	if err != nil {
+		t.Fatalf("Failed to create regex set: %v", err)
+	}
+
+	matched, ok := set.Match("newsletter@example.com")
+	if !ok {
+		t.Fatal("Expected input to match.")
+	}
+
+	if matched != `example\.com$` {
+		t.Fatalf("Expected the first declared pattern to match, got %q.", matched)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	cache := newLRU[int](2)
+	cache.set("a", 1)
+	cache.set("b", 2)
+	cache.get("a")
+	cache.set("c", 3)
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted.")
+	}
+
+	if _, ok := cache.get("a"); !ok {
+		t.Error("Expected recently used entry to be retained.")
+	}
+}
@@ -7,9 +7,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/mail"
-	"regexp"
 	"strings"
 
 	"go.xrstf.de/rudi"
@@ -17,10 +15,12 @@ import (
 )
 
 var Functions = rudi.Functions{
-	"domain":   rudi.NewFunctionBuilder(domainFunc).WithDescription("returns the domain portion of the address' email").Build(),
-	"user":     rudi.NewFunctionBuilder(userFunc).WithDescription("returns the user portion of the address' email").Build(),
-	"matches?": rudi.NewFunctionBuilder(matchesFunc).WithDescription("returns true if the first value matches any of the given expressions").Build(),
-	"header":   rudi.NewFunctionBuilder(docHeaderFunc, headerFunc).WithDescription("returns the first value for the given header or an empty string if the header is not set").Build(),
+	"domain":           rudi.NewFunctionBuilder(domainFunc).WithDescription("returns the domain portion of the address' email").Build(),
+	"user":             rudi.NewFunctionBuilder(userFunc).WithDescription("returns the user portion of the address' email").Build(),
+	"matches?":         rudi.NewFunctionBuilder(matchesFunc).WithDescription("returns true if the first value matches any of the given expressions, sets or regex sets").Build(),
+	"matching-pattern": rudi.NewFunctionBuilder(matchingPatternFunc).WithDescription("returns the first of the given expressions, sets or regex sets that matches the first value (or null)").Build(),
+	"regex-set":        rudi.NewFunctionBuilder(regexSetFunc).WithDescription("precompiles the given expressions and sets of expressions into a regex set").Build(),
+	"header":           rudi.NewFunctionBuilder(docHeaderFunc, headerFunc).WithDescription("returns the first value for the given header or an empty string if the header is not set").Build(),
 }
 
 func domainFunc(val any) (any, error) {
@@ -50,42 +50,81 @@ func userFunc(val any) (any, error) {
 }
 
 func matchesFunc(ctx rudi.Context, input string, patterns ...any) (any, error) {
+	_, matched, err := findMatchingPattern(ctx, input, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return matched, nil
+}
+
+func matchingPatternFunc(ctx rudi.Context, input string, patterns ...any) (any, error) {
+	pattern, matched, err := findMatchingPattern(ctx, input, patterns)
+	if err != nil || !matched {
+		return nil, err
+	}
+
+	return pattern, nil
+}
+
+// findMatchingPattern returns the first pattern that matches the input.
+// Patterns can be strings, sets of strings or RegexSets.
+func findMatchingPattern(ctx rudi.Context, input string, patterns []any) (string, bool, error) {
 	for _, pattern := range patterns {
-		if stringSet, ok := pattern.(sets.Set[string]); ok {
-			matches, err := matchesFunc(ctx, input, stringSetToAnys(stringSet)...)
+		switch p := pattern.(type) {
+		case *RegexSet:
+			if matched, ok := p.Match(input); ok {
+				return matched, true, nil
+			}
+
+		case sets.Set[string]:
+			regexSet, err := NewRegexSet(sets.List(p))
 			if err != nil {
-				return nil, err
+				return "", false, err
 			}
 
-			if matched, _ := matches.(bool); matched {
-				return true, nil
+			if matched, ok := regexSet.Match(input); ok {
+				return matched, true, nil
 			}
-		} else {
+
+		default:
 			s, err := ctx.Coalesce().ToString(pattern)
 			if err != nil {
-				return nil, err
+				return "", false, err
 			}
 
-			re, err := regexp.Compile(s)
+			re, err := compileRegex(s)
 			if err != nil {
-				return nil, fmt.Errorf("invalid expression %q: %w", s, err)
+				return "", false, err
 			}
 
 			if re.MatchString(input) {
-				return true, nil
+				return s, true, nil
 			}
 		}
 	}
 
-	return false, nil
+	return "", false, nil
 }
 
-func stringSetToAnys(s sets.Set[string]) []any {
-	result := make([]any, 0, s.Len())
-	for _, value := range sets.List(s) {
-		result = append(result, value)
+func regexSetFunc(ctx rudi.Context, patterns ...any) (any, error) {
+	var list []string
+
+	for _, pattern := range patterns {
+		if stringSet, ok := pattern.(sets.Set[string]); ok {
+			list = append(list, sets.List(stringSet)...)
+			continue
+		}
+
+		s, err := ctx.Coalesce().ToString(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, s)
 	}
-	return result
+
+	return NewRegexSet(list)
 }
 
 func docHeaderFunc(ctx rudi.Context, name string) (any, error) {
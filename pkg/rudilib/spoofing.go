@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"errors"
+
+	"go.xrstf.de/rudi"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"go.xrstf.de/rudi-lda/pkg/spoofing"
+)
+
+// defaultMaxDistance is the edit distance up to which domains are considered
+// similar.
+const defaultMaxDistance = 1
+
+// SpoofingFunctions are functions for detecting lookalike domains and
+// display names.
+var SpoofingFunctions = rudi.Functions{
+	"skeleton":               rudi.NewFunctionBuilder(skeletonFunc).WithDescription("returns the (case-folded) Unicode TR39 skeleton of the string").Build(),
+	"confusable?":            rudi.NewFunctionBuilder(confusableFunc).WithDescription("returns true if both strings look alike, but are not identical").Build(),
+	"similar-domain":         rudi.NewFunctionBuilder(similarDomainFunc, similarDomainWithDistanceFunc).WithDescription("returns the protected domain that the domain resembles (or null)").Build(),
+	"similar-domain?":        rudi.NewFunctionBuilder(isSimilarDomainFunc, isSimilarDomainWithDistanceFunc).WithDescription("returns true if the domain resembles, but is not, any of the protected domains").Build(),
+	"display-name-mismatch":  rudi.NewFunctionBuilder(displayNameMismatchFunc, displayNameMismatchWithBrandsFunc).WithDescription("returns the brand or domain in the address' display name that does not match its domain (or null)").Build(),
+	"display-name-mismatch?": rudi.NewFunctionBuilder(isDisplayNameMismatchFunc, isDisplayNameMismatchWithBrandsFunc).WithDescription("returns true if the address' display name mentions a brand or domain that does not match its domain").Build(),
+}
+
+func skeletonFunc(s string) (any, error) {
+	return spoofing.Skeleton(s), nil
+}
+
+func confusableFunc(a, b string) (any, error) {
+	return spoofing.Confusable(a, b), nil
+}
+
+func similarDomainFunc(domain string, protected any) (any, error) {
+	return similarDomainWithDistanceFunc(domain, protected, defaultMaxDistance)
+}
+
+func similarDomainWithDistanceFunc(domain string, protected any, maxDistance int64) (any, error) {
+	domains, err := toStrings(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	similar, ok := spoofing.SimilarDomain(domain, domains, int(maxDistance))
+	if !ok {
+		return nil, nil
+	}
+
+	return similar, nil
+}
+
+func isSimilarDomainFunc(domain string, protected any) (any, error) {
+	return isSimilarDomainWithDistanceFunc(domain, protected, defaultMaxDistance)
+}
+
+func isSimilarDomainWithDistanceFunc(domain string, protected any, maxDistance int64) (any, error) {
+	similar, err := similarDomainWithDistanceFunc(domain, protected, maxDistance)
+	return similar != nil, err
+}
+
+func displayNameMismatchFunc(addr map[string]any) (any, error) {
+	return displayNameMismatch(addr, spoofing.DefaultBrands)
+}
+
+func displayNameMismatchWithBrandsFunc(addr map[string]any, brands any) (any, error) {
+	list, err := toStrings(brands)
+	if err != nil {
+		return nil, err
+	}
+
+	return displayNameMismatch(addr, list)
+}
+
+func isDisplayNameMismatchFunc(addr map[string]any) (any, error) {
+	mismatch, err := displayNameMismatchFunc(addr)
+	return mismatch != nil, err
+}
+
+func isDisplayNameMismatchWithBrandsFunc(addr map[string]any, brands any) (any, error) {
+	mismatch, err := displayNameMismatchWithBrandsFunc(addr, brands)
+	return mismatch != nil, err
+}
+
+func displayNameMismatch(addr map[string]any, brands []string) (any, error) {
+	name, _ := addr["name"].(string)
+	address, _ := addr["address"].(string)
+
+	mismatch, ok := spoofing.DisplayNameMismatch(name, address, brands)
+	if !ok {
+		return nil, nil
+	}
+
+	return mismatch, nil
+}
+
+// toStrings accepts vectors of strings and sets of strings.
+func toStrings(val any) ([]string, error) {
+	switch v := val.(type) {
+	case sets.Set[string]:
+		return sets.List(v), nil
+
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New("expected a vector of strings")
+			}
+
+			result = append(result, s)
+		}
+
+		return result, nil
+	}
+
+	return nil, errors.New("expected a vector or set of strings")
+}
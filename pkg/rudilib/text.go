@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"strings"
+
+	"go.xrstf.de/rudi"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// TextFunctions are functions for extracting and normalizing text.
+var TextFunctions = rudi.Functions{
+	"match":               rudi.NewFunctionBuilder(matchFunc).WithDescription("returns the match and all capture groups of the first match of the expression (or null)").Build(),
+	"match-all":           rudi.NewFunctionBuilder(matchAllFunc).WithDescription("returns the matches and capture groups for all matches of the expression").Build(),
+	"match-named":         rudi.NewFunctionBuilder(matchNamedFunc).WithDescription("returns an object with all named capture groups of the first match of the expression (or null)").Build(),
+	"regex-replace":       rudi.NewFunctionBuilder(regexReplaceFunc).WithDescription("replaces all matches of the expression (supports $1 and ${name} references)").Build(),
+	"casefold":            rudi.NewFunctionBuilder(casefoldFunc).WithDescription("folds the case of the string for case-insensitive comparisons").Build(),
+	"nfkc":                rudi.NewFunctionBuilder(nfkcFunc).WithDescription("normalizes the string to Unicode NFKC form").Build(),
+	"collapse-whitespace": rudi.NewFunctionBuilder(collapseWhitespaceFunc).WithDescription("trims the string and replaces all runs of whitespace with a single space").Build(),
+}
+
+func matchFunc(input string, pattern string) (any, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	match := re.FindStringSubmatch(input)
+	if match == nil {
+		return nil, nil
+	}
+
+	return stringsToAnys(match), nil
+}
+
+func matchAllFunc(input string, pattern string) (any, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []any{}
+	for _, match := range re.FindAllStringSubmatch(input, -1) {
+		result = append(result, stringsToAnys(match))
+	}
+
+	return result, nil
+}
+
+func matchNamedFunc(input string, pattern string) (any, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	match := re.FindStringSubmatch(input)
+	if match == nil {
+		return nil, nil
+	}
+
+	result := map[string]any{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			result[name] = match[i]
+		}
+	}
+
+	return result, nil
+}
+
+func regexReplaceFunc(input string, pattern string, replacement string) (any, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.ReplaceAllString(input, replacement), nil
+}
+
+func casefoldFunc(input string) (any, error) {
+	return cases.Fold().String(input), nil
+}
+
+func nfkcFunc(input string) (any, error) {
+	return norm.NFKC.String(input), nil
+}
+
+func collapseWhitespaceFunc(input string) (any, error) {
+	return strings.Join(strings.Fields(input), " "), nil
+}
+
+func stringsToAnys(values []string) []any {
+	result := make([]any, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
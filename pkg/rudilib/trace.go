@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.xrstf.de/rudi"
+	"go.xrstf.de/rudi/pkg/lang/ast"
+)
+
+// Call is a single function call recorded while tracing a script. Rudi does
+// not keep source positions, so calls are identified by their expression.
+type Call struct {
+	Script     string `json:"script"`
+	Depth      int    `json:"depth"`
+	Expression string `json:"expression"`
+	Result     string `json:"result,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (c Call) String() string {
+	outcome := c.Result
+	if c.Error != "" {
+		outcome = "error: " + c.Error
+	}
+
+	return fmt.Sprintf("%s: %s%s => %s", c.Script, strings.Repeat("  ", c.Depth), c.Expression, outcome)
+}
+
+// Tracer records all calls of the functions provided by Rudi-LDA (but not
+// Rudi's built-in functions like `if`). Calls are passed to the emit
+// function in the order in which they were made, once the outermost call
+// has returned.
+type Tracer struct {
+	emit    func(Call)
+	pending []Call
+	depth   int
+}
+
+func NewTracer(emit func(Call)) *Tracer {
+	return &Tracer{
+		emit: emit,
+	}
+}
+
+type tracerKey struct{}
+
+// WithTracer returns a context that makes ProcessMessage trace all
+// function calls.
+func WithTracer(ctx context.Context, tracer *Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, tracer)
+}
+
+func getTracer(ctx context.Context) *Tracer {
+	tracer, _ := ctx.Value(tracerKey{}).(*Tracer)
+	return tracer
+}
+
+func (t *Tracer) wrap(script string, funcs rudi.Functions) rudi.Functions {
+	wrapped := rudi.Functions{}
+	for name, fn := range funcs {
+		wrapped[name] = &tracedFunction{
+			Function: fn,
+			tracer:   t,
+			script:   script,
+			name:     name,
+		}
+	}
+
+	return wrapped
+}
+
+type tracedFunction struct {
+	rudi.Function
+
+	tracer *Tracer
+	script string
+	name   string
+}
+
+func (f *tracedFunction) Evaluate(ctx rudi.Context, args []ast.Expression) (any, error) {
+	t := f.tracer
+
+	parts := []string{f.name}
+	for _, arg := range args {
+		parts = append(parts, arg.String())
+	}
+
+	idx := len(t.pending)
+	t.pending = append(t.pending, Call{
+		Script:     f.script,
+		Depth:      t.depth,
+		Expression: "(" + strings.Join(parts, " ") + ")",
+	})
+
+	t.depth++
+	result, err := f.Function.Evaluate(ctx, args)
+	t.depth--
+
+	if err != nil {
+		t.pending[idx].Error = err.Error()
+	} else {
+		t.pending[idx].Result = formatResult(result)
+	}
+
+	if t.depth == 0 {
+		for _, call := range t.pending {
+			t.emit(call)
+		}
+
+		t.pending = nil
+	}
+
+	return result, err
+}
+
+func formatResult(result any) string {
+	switch r := result.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", r)
+	default:
+		return fmt.Sprintf("%v", r)
+	}
+}
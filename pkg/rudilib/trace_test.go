@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.xrstf.de/rudi-lda/pkg/test/emails"
+)
+
+func TestTracer(t *testing.T) {
+	scriptFile := filepath.Join(t.TempDir(), "test.rudi")
+	script := `(if (matches? (domain .from.address) "^github") (user .from.address) "nope")`
+
+	if err := os.WriteFile(scriptFile, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []Call
+	ctx := WithTracer(context.Background(), NewTracer(func(c Call) {
+		calls = append(calls, c)
+	}))
+
+	result, err := ProcessMessage(ctx, scriptFile, emails.GitHubIssueClosed(), nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	if result != "notifications" {
+		t.Fatalf("Expected script to return \"notifications\", got %v.", result)
+	}
+
+	// `if` is a built-in function and not traced
+	expected := []struct {
+		function string
+		depth    int
+		result   string
+	}{
+		{function: "matches?", depth: 0, result: "true"},
+		{function: "domain", depth: 1, result: `"github.com"`},
+		{function: "user", depth: 0, result: `"notifications"`},
+	}
+
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d calls, got %v.", len(expected), calls)
+	}
+
+	for i, call := range calls {
+		exp := expected[i]
+
+		if !strings.HasPrefix(call.Expression, "("+exp.function+" ") {
+			t.Errorf("Expected call %d to be %s, got %s.", i, exp.function, call.Expression)
+		}
+
+		if call.Depth != exp.depth || call.Result != exp.result || call.Script != "test.rudi" {
+			t.Errorf("Expected call %d to be at depth %d with result %s, got %+v.", i, exp.depth, exp.result, call)
+		}
+	}
+}
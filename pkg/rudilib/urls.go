@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package rudilib
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"go.xrstf.de/rudi"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// URLFunctions are functions for analyzing the links in an e-mail (.urls).
+var URLFunctions = rudi.Functions{
+	"registrable-domain": rudi.NewFunctionBuilder(registrableDomainFunc).WithDescription("returns the registrable domain (public suffix plus one label) of a URL, host or address").Build(),
+	"ip-host?":           rudi.NewFunctionBuilder(ipHostFunc).WithDescription("returns true if the URL's host is an IP literal (including obfuscated numeric forms)").Build(),
+	"shortener?":         rudi.NewFunctionBuilder(shortenerFunc).WithDescription("returns true if the URL's host is a known URL shortener").Build(),
+	"url-domains":        rudi.NewFunctionBuilder(urlDomainsFunc).WithDescription("returns the unique registrable domains of the given URLs").Build(),
+}
+
+// hostOf accepts URL objects (from .urls), address objects, URLs, addresses
+// and plain hosts.
+func hostOf(val any) (string, error) {
+	if thing, ok := val.(map[string]any); ok {
+		if host, ok := thing["host"]; ok {
+			val = host
+		} else {
+			val = thing["address"]
+		}
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return "", errors.New("cannot deal with provided value")
+	}
+
+	s = strings.TrimSpace(s)
+
+	if strings.Contains(s, "://") {
+		parsed, err := url.Parse(s)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.ToLower(parsed.Hostname()), nil
+	}
+
+	if idx := strings.LastIndex(s, "@"); idx >= 0 {
+		s = s[idx+1:]
+	}
+
+	return strings.ToLower(s), nil
+}
+
+func registrableDomainFunc(val any) (any, error) {
+	host, err := hostOf(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return email.RegistrableDomain(host), nil
+}
+
+func ipHostFunc(val any) (any, error) {
+	host, err := hostOf(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return email.IsIPHost(host), nil
+}
+
+func shortenerFunc(val any) (any, error) {
+	host, err := hostOf(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return email.IsShortener(host), nil
+}
+
+func urlDomainsFunc(urls []any) (any, error) {
+	result := []any{}
+	seen := map[string]struct{}{}
+
+	for _, u := range urls {
+		host, err := hostOf(u)
+		if err != nil {
+			return nil, err
+		}
+
+		domain := email.RegistrableDomain(host)
+		if _, ok := seen[domain]; ok || domain == "" {
+			continue
+		}
+
+		seen[domain] = struct{}{}
+		result = append(result, domain)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package screener
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/fs"
+	"go.xrstf.de/rudi-lda/pkg/lists"
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+)
+
+type Status string
+
+const (
+	Accepted Status = "accepted"
+	Rejected Status = "rejected"
+	Unknown  Status = "unknown"
+)
+
+const (
+	// AcceptedList and RejectedList are the names of the list files in the
+	// datadir that contain the user's decisions.
+	AcceptedList = "screener-accepted"
+	RejectedList = "screener-rejected"
+
+	// DefaultFolder is the folder for e-mails from unknown senders. The
+	// user decides about senders by moving their e-mails into its Accept
+	// and Reject subfolders.
+	DefaultFolder = "Screener"
+
+	// Rule is recorded for e-mails that are quarantined because their
+	// sender was rejected.
+	Rule = "screener"
+)
+
+// ErrNoSender is returned for e-mails without a sender address (e.g. bounces),
+// which nobody can decide about.
+var ErrNoSender = errors.New("e-mail has no sender address")
+
+// AcceptFolder returns the folder that accepts the senders of all e-mails
+// moved into it.
+func AcceptFolder(folder string) string {
+	return folder + ".Accept"
+}
+
+// RejectFolder returns the folder that rejects the senders of all e-mails
+// moved into it.
+func RejectFolder(folder string) string {
+	return folder + ".Reject"
+}
+
+// Screener remembers which senders the user wants to hear from. Decisions
+// are kept in two list files, so that entries can be addresses or domains
+// and the lists can be managed like all other lists.
+type Screener struct {
+	directory   string
+	scannedFile string
+}
+
+func New(datadir string) *Screener {
+	return &Screener{
+		directory:   lists.Directory(datadir),
+		scannedFile: filepath.Join(datadir, "screener-scanned"),
+	}
+}
+
+// Sender returns the address that decisions are made about.
+func Sender(msg *email.Message) string {
+	from := msg.GetFrom()
+	if from == nil {
+		return ""
+	}
+
+	return strings.ToLower(from.Address)
+}
+
+// Status returns the decision for the given address. If both lists match,
+// the more specific entry wins, i.e. an accepted address from a rejected
+// domain is accepted. An empty address results in ErrNoSender.
+func (s *Screener) Status(address string) (Status, error) {
+	if address == "" {
+		return Unknown, ErrNoSender
+	}
+
+	accepted, isAccepted, err := s.match(AcceptedList, address)
+	if err != nil {
+		return Unknown, err
+	}
+
+	rejected, isRejected, err := s.match(RejectedList, address)
+	if err != nil {
+		return Unknown, err
+	}
+
+	switch {
+	case isAccepted && isRejected:
+		if strings.EqualFold(accepted, address) && !strings.EqualFold(rejected, address) {
+			return Accepted, nil
+		}
+
+		return Rejected, nil
+	case isAccepted:
+		return Accepted, nil
+	case isRejected:
+		return Rejected, nil
+	default:
+		return Unknown, nil
+	}
+}
+
+func (s *Screener) match(name string, address string) (string, bool, error) {
+	filename, err := lists.Filename(s.directory, name)
+	if err != nil {
+		return "", false, err
+	}
+
+	list, err := lists.Load(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, nil
+		}
+
+		return "", false, err
+	}
+
+	entry, ok := list.Match(address)
+
+	return entry, ok, nil
+}
+
+// Accept accepts the given addresses or domains, undoing previous
+// rejections. It returns the entries that were newly accepted.
+func (s *Screener) Accept(entries []string) ([]string, error) {
+	return s.decide(AcceptedList, RejectedList, entries)
+}
+
+// Reject rejects the given addresses or domains, undoing previous
+// acceptances. It returns the entries that were newly rejected.
+func (s *Screener) Reject(entries []string) ([]string, error) {
+	return s.decide(RejectedList, AcceptedList, entries)
+}
+
+func (s *Screener) decide(add string, remove string, entries []string) ([]string, error) {
+	addFile, err := lists.Filename(s.directory, add)
+	if err != nil {
+		return nil, err
+	}
+
+	removeFile, err := lists.Filename(s.directory, remove)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := lists.Remove(removeFile, entries); err != nil {
+		return nil, fmt.Errorf("failed to update list %s: %w", remove, err)
+	}
+
+	added, err := lists.Add(addFile, entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update list %s: %w", add, err)
+	}
+
+	return added, nil
+}
+
+// Changed returns true if decisions have been made since the last call to
+// Scanned, i.e. if e-mails in the screener folder might have to be moved.
+func (s *Screener) Changed() (bool, error) {
+	info, err := os.Stat(s.scannedFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	for _, name := range []string{AcceptedList, RejectedList} {
+		filename, err := lists.Filename(s.directory, name)
+		if err != nil {
+			return false, err
+		}
+
+		listInfo, err := os.Stat(filename)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return false, err
+		}
+
+		// filesystems with coarse timestamps can report identical times,
+		// so rather scan once too often
+		if !listInfo.ModTime().Before(info.ModTime()) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Scanned records that the screener folder has been checked for e-mails of
+// senders that were decided about before the given time.
+func (s *Screener) Scanned(t time.Time) error {
+	if err := os.WriteFile(s.scannedFile, nil, fs.FilePermissions); err != nil {
+		return err
+	}
+
+	return os.Chtimes(s.scannedFile, t, t)
+}
+
+// Seed accepts all recipients of the e-mails in the given folder (usually
+// the Sent folder), because whoever the user writes to is known to them.
+// Rejected senders are left alone. It returns the newly accepted addresses.
+func (s *Screener) Seed(md *maildir.Maildir, folder string) ([]string, error) {
+	files, err := md.List(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+
+	var recipients []string
+
+	for _, file := range files {
+		msg, err := md.Read(file)
+		if err != nil {
+			// unparseable e-mails are no reason to stop
+			continue
+		}
+
+		for _, header := range []string{"To", "Cc", "Bcc"} {
+			addresses, err := msg.Header.AddressList(header)
+			if err != nil {
+				continue
+			}
+
+			for _, address := range addresses {
+				key := strings.ToLower(address.Address)
+				if _, ok := seen[key]; ok || key == "" {
+					continue
+				}
+
+				seen[key] = struct{}{}
+
+				status, err := s.Status(key)
+				if err != nil {
+					return nil, err
+				}
+
+				if status == Unknown {
+					recipients = append(recipients, key)
+				}
+			}
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+
+	filename, err := lists.Filename(s.directory, AcceptedList)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := lists.Add(filename, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update list %s: %w", AcceptedList, err)
+	}
+
+	return added, nil
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package screener
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/maildir"
+)
+
+func TestStatus(t *testing.T) {
+	s := New(t.TempDir())
+
+	if _, err := s.Accept([]string{"friend@example.com", "example.org", "boss@example.net"}); err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	if _, err := s.Reject([]string{"spammer@example.org", "example.net", "newsletter@example.com"}); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+
+	testcases := []struct {
+		address  string
+		expected Status
+	}{
+		{address: "stranger@example.com", expected: Unknown},
+		{address: "friend@example.com", expected: Accepted},
+		{address: "colleague@mail.example.org", expected: Accepted},
+		{address: "newsletter@example.com", expected: Rejected},
+		// the address is more specific than the domain
+		{address: "spammer@example.org", expected: Rejected},
+		{address: "boss@example.net", expected: Accepted},
+		{address: "other@example.net", expected: Rejected},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.address, func(t *testing.T) {
+			status, err := s.Status(testcase.address)
+			if err != nil {
+				t.Fatalf("Failed to screen: %v", err)
+			}
+
+			if status != testcase.expected {
+				t.Fatalf("Expected %s, got %s.", testcase.expected, status)
+			}
+		})
+	}
+}
+
+func TestNoSender(t *testing.T) {
+	s := New(t.TempDir())
+
+	if _, err := s.Status(""); !errors.Is(err, ErrNoSender) {
+		t.Fatalf("Expected ErrNoSender, got %v.", err)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	s := New(t.TempDir())
+
+	assertChanged := func(expected bool) {
+		t.Helper()
+
+		changed, err := s.Changed()
+		if err != nil {
+			t.Fatalf("Failed to check for changes: %v", err)
+		}
+
+		if changed != expected {
+			t.Fatalf("Expected changed to be %v.", expected)
+		}
+	}
+
+	// never scanned before
+	assertChanged(true)
+
+	if _, err := s.Accept([]string{"friend@example.com"}); err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	if err := s.Scanned(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to record scan: %v", err)
+	}
+
+	assertChanged(false)
+
+	if err := s.Scanned(time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("Failed to record scan: %v", err)
+	}
+
+	assertChanged(true)
+}
+
+func TestChangeDecision(t *testing.T) {
+	s := New(t.TempDir())
+
+	if _, err := s.Reject([]string{"friend@example.com"}); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+
+	if _, err := s.Accept([]string{"friend@example.com"}); err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+
+	status, err := s.Status("friend@example.com")
+	if err != nil {
+		t.Fatalf("Failed to screen: %v", err)
+	}
+
+	if status != Accepted {
+		t.Fatalf("Expected sender to be accepted, got %s.", status)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	base := t.TempDir()
+	s := New(t.TempDir())
+
+	md, err := maildir.New(base)
+	if err != nil {
+		t.Fatalf("Failed to create Maildir: %v", err)
+	}
+
+	sent := filepath.Join(base, ".Sent", "cur")
+	if err := os.MkdirAll(sent, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string]string{
+		"first:2,S":  "From: me@example.com\r\nTo: Friend <Friend@example.com>, other@example.org\r\nSubject: hello\r\n\r\nHi.\r\n",
+		"second:2,S": "From: me@example.com\r\nTo: friend@example.com\r\nCc: spammer@example.net\r\nSubject: hello again\r\n\r\nHi.\r\n",
+	}
+
+	for name, content := range messages {
+		if err := os.WriteFile(filepath.Join(sent, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// rejected senders must stay rejected
+	if _, err := s.Reject([]string{"spammer@example.net"}); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+
+	accepted, err := s.Seed(md, "Sent")
+	if err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+
+	expected := []string{"friend@example.com", "other@example.org"}
+	if !reflect.DeepEqual(accepted, expected) {
+		t.Fatalf("Expected %v to be accepted, got %v.", expected, accepted)
+	}
+
+	status, err := s.Status("spammer@example.net")
+	if err != nil {
+		t.Fatalf("Failed to screen: %v", err)
+	}
+
+	if status != Rejected {
+		t.Fatalf("Expected sender to stay rejected, got %s.", status)
+	}
+}
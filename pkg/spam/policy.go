@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package spam
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Action is what happens to e-mails with a given status.
+type Action string
+
+const (
+	// ActionDiscard drops the e-mail (after backing it up, if enabled).
+	ActionDiscard Action = "discard"
+	// ActionJunk delivers the e-mail into the Junk folder, unless the folder
+	// script decides otherwise.
+	ActionJunk Action = "junk"
+	// ActionTag prefixes the subject.
+	ActionTag Action = "tag"
+	// ActionKeyword sets the $Junk keyword using Dovecot's keywords file.
+	ActionKeyword Action = "keyword"
+	// ActionSpam treats maybe-spam like spam.
+	ActionSpam Action = "spam"
+)
+
+// JunkKeyword is the IMAP keyword that mail clients use to mark spam.
+const JunkKeyword = "$Junk"
+
+// Policy configures the actions for spam and maybe-spam e-mails.
+type Policy map[Status][]Action
+
+// DefaultPolicy discards spam and delivers maybe-spam like ham.
+var DefaultPolicy = Policy{
+	Spam: {ActionDiscard},
+}
+
+// NewPolicy parses and validates the actions for spam and maybe-spam.
+func NewPolicy(spamActions []string, maybeSpamActions []string) (Policy, error) {
+	policy := Policy{}
+
+	for _, action := range spamActions {
+		switch a := Action(action); a {
+		case ActionDiscard, ActionJunk, ActionTag, ActionKeyword:
+			policy[Spam] = append(policy[Spam], a)
+		default:
+			return nil, fmt.Errorf("invalid action %q for spam", action)
+		}
+	}
+
+	for _, action := range maybeSpamActions {
+		switch a := Action(action); a {
+		case ActionSpam, ActionJunk, ActionTag, ActionKeyword:
+			policy[MaybeSpam] = append(policy[MaybeSpam], a)
+		default:
+			return nil, fmt.Errorf("invalid action %q for maybe-spam", action)
+		}
+	}
+
+	return policy, nil
+}
+
+// Has returns true if the action is configured for the given status.
+func (p Policy) Has(status Status, action Action) bool {
+	return slices.Contains(p[status], action)
+}
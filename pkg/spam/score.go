@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package spam
+
+import (
+	"errors"
+	"fmt"
+
+	"go.xrstf.de/rudi"
+)
+
+// scorer collects the rules that fired during a single script run.
+type scorer struct {
+	hits []Hit
+}
+
+func newScorer() *scorer {
+	return &scorer{}
+}
+
+func (s *scorer) functions() rudi.Functions {
+	return rudi.Functions{
+		"score!": rudi.NewFunctionBuilder(s.scoreFunc).WithDescription("adds the score (negative for hammy rules) of a rule to the e-mail's total spam score, returns the new total").Build(),
+	}
+}
+
+func (s *scorer) scoreFunc(score any, rule string) (any, error) {
+	value, err := toFloat(score)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule == "" {
+		return nil, errors.New("rule name must not be empty")
+	}
+
+	// a rule that fires multiple times only counts once
+	for i, hit := range s.hits {
+		if hit.Rule == rule {
+			s.hits[i].Score = value
+			return s.total(), nil
+		}
+	}
+
+	s.hits = append(s.hits, Hit{
+		Rule:  rule,
+		Score: value,
+	})
+
+	return s.total(), nil
+}
+
+func (s *scorer) total() float64 {
+	total := 0.0
+	for _, hit := range s.hits {
+		total += hit.Score
+	}
+
+	return total
+}
+
+func (s *scorer) result(thresholds Thresholds) *Result {
+	var hits []Hit
+	if len(s.hits) > 0 {
+		hits = make([]Hit, len(s.hits))
+		copy(hits, s.hits)
+		sortHits(hits)
+	}
+
+	r := &Result{
+		Status: Ham,
+		Score:  s.total(),
+		Hits:   hits,
+	}
+
+	switch {
+	case r.Score >= thresholds.Spam:
+		r.Status = Spam
+	case r.Score >= thresholds.MaybeSpam:
+		r.Status = MaybeSpam
+	}
+
+	if len(hits) > 0 {
+		r.Rule = hits[0].Rule
+	}
+
+	return r
+}
+
+func toFloat(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("score must be a number, got %T", value)
+	}
+}
@@ -7,6 +7,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.xrstf.de/rudi"
 
 	"go.xrstf.de/rudi-lda/pkg/email"
 	"go.xrstf.de/rudi-lda/pkg/rudilib"
@@ -20,18 +26,86 @@ const (
 	Ham       Status = "ham"
 )
 
+const (
+	// VerdictHeader carries the result of the spam check from the antispam
+	// processor to later processors (and the user).
+	VerdictHeader = "X-Rudi-LDA-Antispam"
+	// StatusHeader lists all rules in the style of SpamAssassin.
+	StatusHeader = "X-Spam-Status"
+)
+
+// Thresholds map the total score of all rules to a status.
+type Thresholds struct {
+	MaybeSpam float64
+	Spam      float64
+}
+
+var DefaultThresholds = Thresholds{
+	MaybeSpam: 5,
+	Spam:      10,
+}
+
+// NewThresholds validates the thresholds. E-mails can only be maybe-spam
+// if the maybe-spam threshold is not larger than the spam threshold.
+func NewThresholds(maybeSpam float64, spam float64) (Thresholds, error) {
+	if maybeSpam > spam {
+		return Thresholds{}, fmt.Errorf("maybe-spam threshold (%g) must not be larger than the spam threshold (%g)", maybeSpam, spam)
+	}
+
+	return Thresholds{
+		MaybeSpam: maybeSpam,
+		Spam:      spam,
+	}, nil
+}
+
+// Hit is a rule that fired using score!.
+type Hit struct {
+	Rule  string  `json:"rule"`
+	Score float64 `json:"score"`
+}
+
 type Result struct {
 	Status Status `json:"status"`
-	Rule   string `json:"rule"`
+	// Rule is the deciding rule, i.e. the rule given to spam/ham/maybe-spam
+	// or the rule with the highest score.
+	Rule  string  `json:"rule"`
+	Score float64 `json:"score,omitempty"`
+	Hits  []Hit   `json:"hits,omitempty"`
 }
 
-func Check(ctx context.Context, scriptFile string, msg *email.Message) (*Result, error) {
-	result, err := rudilib.ProcessMessage(ctx, scriptFile, msg, nil, Functions)
+// ScriptFunctions returns all functions available to spam scripts, including
+// score!. Scores only accumulate within the returned set of functions, so a
+// new set should be used for every evaluation.
+func ScriptFunctions() rudi.Functions {
+	return rudi.Functions{}.Add(Functions).Add(newScorer().functions())
+}
+
+// Check runs the spam script. Scripts can either return a verdict (using
+// spam, ham or maybe-spam) or add scores using score!, in which case the
+// total score determines the status. A returned verdict always takes
+// precedence over the scores.
+func Check(ctx context.Context, scriptFile string, msg *email.Message, thresholds Thresholds) (*Result, error) {
+	scorer := newScorer()
+
+	funcs := rudi.Functions{}.Add(Functions).Add(scorer.functions())
+
+	result, err := rudilib.ProcessMessage(ctx, scriptFile, msg, nil, nil, funcs)
 	if err != nil {
 		return nil, err
 	}
 
-	return parseResult(result), nil
+	verdict := parseResult(result)
+	if verdict == nil && len(scorer.hits) == 0 {
+		return nil, nil
+	}
+
+	r := scorer.result(thresholds)
+	if verdict != nil {
+		r.Status = verdict.Status
+		r.Rule = verdict.Rule
+	}
+
+	return r, nil
 }
 
 func parseResult(result any) *Result {
@@ -50,5 +124,104 @@ func parseResult(result any) *Result {
 		return nil
 	}
 
-	return &r
+	switch r.Status {
+	case Spam, MaybeSpam, Ham:
+		return &r
+	default:
+		return nil
+	}
+}
+
+// Rules returns the names of all rules that contributed to the result.
+func (r *Result) Rules() []string {
+	var rules []string
+
+	seen := map[string]struct{}{}
+	for _, hit := range r.Hits {
+		if _, ok := seen[hit.Rule]; !ok {
+			seen[hit.Rule] = struct{}{}
+			rules = append(rules, hit.Rule)
+		}
+	}
+
+	if _, ok := seen[r.Rule]; !ok && r.Rule != "" {
+		rules = append(rules, r.Rule)
+	}
+
+	return rules
+}
+
+// Verdict renders the result for the VerdictHeader, e.g.
+// "status:maybe-spam,score:6.5,rule:foo".
+func (r *Result) Verdict() string {
+	return fmt.Sprintf("status:%s,score:%.1f,rule:%s", r.Status, r.Score, r.Rule)
+}
+
+// ParseVerdict parses the value of a VerdictHeader. It returns nil if the
+// value is invalid.
+func ParseVerdict(value string) *Result {
+	r := &Result{}
+
+	for value != "" {
+		var field string
+
+		// the rule is last and may contain commas
+		if strings.HasPrefix(value, "rule:") {
+			field, value = value, ""
+		} else {
+			field, value, _ = strings.Cut(value, ",")
+		}
+
+		key, val, _ := strings.Cut(field, ":")
+
+		switch key {
+		case "status":
+			r.Status = Status(val)
+		case "rule":
+			r.Rule = val
+		case "score":
+			score, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil
+			}
+
+			r.Score = score
+		}
+	}
+
+	switch r.Status {
+	case Spam, MaybeSpam, Ham:
+		return r
+	default:
+		return nil
+	}
+}
+
+// Header renders the result in the style of SpamAssassin's X-Spam-Status
+// header, e.g. "Yes, score=12.5 required=10.0 tests=foo=10.0,bar=2.5".
+func (r *Result) Header(thresholds Thresholds) string {
+	flag := "No"
+	if r.Status != Ham {
+		flag = "Yes"
+	}
+
+	tests := make([]string, 0, len(r.Hits))
+	for _, hit := range r.Hits {
+		tests = append(tests, fmt.Sprintf("%s=%.1f", hit.Rule, hit.Score))
+	}
+
+	header := fmt.Sprintf("%s, score=%.1f required=%.1f", flag, r.Score, thresholds.Spam)
+	if len(tests) > 0 {
+		header += " tests=" + strings.Join(tests, ",")
+	}
+
+	return header
+}
+
+// sortHits orders hits by descending score, keeping the order of rules
+// with identical scores.
+func sortHits(hits []Hit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
 }
@@ -56,6 +56,42 @@ func TestCheck(t *testing.T) {
 				Rule:   "foo",
 			},
 		},
+		{
+			script: `(score! 2.5 "foo")`,
+			expected: &Result{
+				Status: Ham,
+				Rule:   "foo",
+				Score:  2.5,
+				Hits:   []Hit{{Rule: "foo", Score: 2.5}},
+			},
+		},
+		{
+			script: `(score! 2.5 "foo") (score! 3 "bar")`,
+			expected: &Result{
+				Status: MaybeSpam,
+				Rule:   "bar",
+				Score:  5.5,
+				Hits:   []Hit{{Rule: "bar", Score: 3}, {Rule: "foo", Score: 2.5}},
+			},
+		},
+		{
+			script: `(score! 8 "foo") (score! 8 "foo") (score! 4 "bar")`,
+			expected: &Result{
+				Status: Spam,
+				Rule:   "foo",
+				Score:  12,
+				Hits:   []Hit{{Rule: "foo", Score: 8}, {Rule: "bar", Score: 4}},
+			},
+		},
+		{
+			script: `(score! 12 "foo") (ham "allowlisted")`,
+			expected: &Result{
+				Status: Ham,
+				Rule:   "allowlisted",
+				Score:  12,
+				Hits:   []Hit{{Rule: "foo", Score: 12}},
+			},
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -66,7 +102,7 @@ func TestCheck(t *testing.T) {
 			}
 			defer os.Remove(scriptFile)
 
-			result, err := Check(ctx, scriptFile, msg)
+			result, err := Check(ctx, scriptFile, msg, DefaultThresholds)
 			if err != nil {
 				t.Fatalf("Failed to perform spam check: %v", err)
 			}
@@ -77,3 +113,92 @@ func TestCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestResultHeader(t *testing.T) {
+	result := &Result{
+		Status: MaybeSpam,
+		Rule:   "bar",
+		Score:  5.5,
+		Hits:   []Hit{{Rule: "bar", Score: 3}, {Rule: "foo", Score: 2.5}},
+	}
+
+	expected := "Yes, score=5.5 required=10.0 tests=bar=3.0,foo=2.5"
+	if header := result.Header(DefaultThresholds); header != expected {
+		t.Fatalf("Expected %q, got %q.", expected, header)
+	}
+
+	result = &Result{Status: Ham}
+
+	expected = "No, score=0.0 required=10.0"
+	if header := result.Header(DefaultThresholds); header != expected {
+		t.Fatalf("Expected %q, got %q.", expected, header)
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	testcases := []struct {
+		value    string
+		expected *Result
+	}{
+		{
+			value:    "",
+			expected: nil,
+		},
+		{
+			value:    "status:unknown,rule:foo",
+			expected: nil,
+		},
+		{
+			value:    "status:spam,rule:foo",
+			expected: &Result{Status: Spam, Rule: "foo"},
+		},
+		{
+			value:    "status:maybe-spam,score:6.5,rule:foo,bar",
+			expected: &Result{Status: MaybeSpam, Score: 6.5, Rule: "foo,bar"},
+		},
+		{
+			value:    (&Result{Status: Ham, Score: -2, Rule: "friend"}).Verdict(),
+			expected: &Result{Status: Ham, Score: -2, Rule: "friend"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.value, func(t *testing.T) {
+			result := ParseVerdict(testcase.value)
+
+			if !cmp.Equal(testcase.expected, result) {
+				t.Fatalf("Expected %+v, got %+v", testcase.expected, result)
+			}
+		})
+	}
+}
+
+func TestNewThresholds(t *testing.T) {
+	testcases := []struct {
+		maybeSpam float64
+		spam      float64
+		invalid   bool
+	}{
+		{maybeSpam: 5, spam: 10},
+		{maybeSpam: 10, spam: 10},
+		{maybeSpam: -5, spam: 0},
+		{maybeSpam: 10, spam: 5, invalid: true},
+	}
+
+	for _, testcase := range testcases {
+		thresholds, err := NewThresholds(testcase.maybeSpam, testcase.spam)
+		if testcase.invalid {
+			if err == nil {
+				t.Errorf("Expected %v/%v to be invalid.", testcase.maybeSpam, testcase.spam)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Expected %v/%v to be valid, got %v.", testcase.maybeSpam, testcase.spam, err)
+		} else if thresholds.MaybeSpam != testcase.maybeSpam || thresholds.Spam != testcase.spam {
+			t.Errorf("Expected thresholds %v/%v, got %+v.", testcase.maybeSpam, testcase.spam, thresholds)
+		}
+	}
+}
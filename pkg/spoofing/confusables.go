@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package spoofing
+
+// confusables maps characters to their prototype, following the Unicode
+// TR39 confusables table (https://www.unicode.org/Public/security/latest/confusables.txt).
+// Only the subset that maps to ASCII letters and digits is included, as
+// that is what lookalike domains and display names are made of. Compatibility
+// characters (e.g. fullwidth or mathematical letters) are handled by NFKC
+// normalization and all input is case-folded before this table is applied,
+// so entries for uppercase ASCII letters would never match.
+var confusables = map[rune]string{
+	// ASCII
+	'0': "O",
+	'1': "l",
+	'|': "l",
+	'm': "rn",
+
+	// Latin
+	'ı': "i",
+	'ɑ': "a",
+	'ɡ': "g",
+	'ɩ': "i",
+	'ʋ': "u",
+	'ʏ': "y",
+	'ȷ': "j",
+	'ƅ': "b",
+	'ǀ': "l",
+	'ſ': "f",
+	'ꞵ': "B",
+	'ꮃ': "W",
+
+	// Cyrillic
+	'а': "a",
+	'в': "B",
+	'е': "e",
+	'ё': "ë",
+	'һ': "h",
+	'і': "i",
+	'ї': "ï",
+	'ј': "j",
+	'к': "K",
+	'ԁ': "d",
+	'ԛ': "q",
+	'ԝ': "w",
+	'ӏ': "l",
+	'м': "M",
+	'н': "H",
+	'о': "o",
+	'п': "n",
+	'р': "p",
+	'с': "c",
+	'т': "T",
+	'у': "y",
+	'х': "x",
+	'ѕ': "s",
+	'ь': "b",
+	'ү': "y",
+	'А': "A",
+	'В': "B",
+	'Е': "E",
+	'З': "3",
+	'І': "l",
+	'Ј': "J",
+	'К': "K",
+	'М': "M",
+	'Н': "H",
+	'О': "O",
+	'Р': "P",
+	'С': "C",
+	'Т': "T",
+	'Х': "X",
+	'Ү': "Y",
+	'Ѕ': "S",
+	'Ԁ': "D",
+	'Ԛ': "Q",
+	'Ԝ': "W",
+	'Ӏ': "l",
+
+	// Greek
+	'α': "a",
+	'ι': "i",
+	'κ': "K",
+	'ν': "v",
+	'ο': "o",
+	'ρ': "p",
+	'σ': "o",
+	'τ': "T",
+	'υ': "u",
+	'γ': "y",
+	'Α': "A",
+	'Β': "B",
+	'Ε': "E",
+	'Ζ': "Z",
+	'Η': "H",
+	'Ι': "l",
+	'Κ': "K",
+	'Μ': "M",
+	'Ν': "N",
+	'Ο': "O",
+	'Ρ': "P",
+	'Τ': "T",
+	'Υ': "Y",
+	'Χ': "X",
+
+	// Armenian
+	'օ': "o",
+	'ս': "u",
+	'հ': "h",
+	'ո': "n",
+	'զ': "q",
+}
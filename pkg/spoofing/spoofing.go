@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package spoofing
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/xrash/smetrics"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+)
+
+// DefaultBrands are the brand names checked in display names if no brands
+// are configured.
+var DefaultBrands = []string{
+	"amazon",
+	"apple",
+	"dhl",
+	"ebay",
+	"facebook",
+	"fedex",
+	"google",
+	"instagram",
+	"microsoft",
+	"netflix",
+	"paypal",
+	"sparkasse",
+	"ups",
+}
+
+// Skeleton returns the TR39 skeleton of a string, i.e. a form in which
+// visually confusable strings are identical. Unlike TR39 skeletons, the
+// result is case-folded, so that e.g. "PayPal" and "paypa1" share the
+// same skeleton.
+func Skeleton(s string) string {
+	fold := cases.Fold()
+	s = fold.String(norm.NFKC.String(s))
+
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		// prototypes can be uppercase and need to be looked up again
+		// (e.g. Cyrillic "м" is "M", which in turn is "rn")
+		for _, folded := range fold.String(prototype(r)) {
+			b.WriteString(prototype(folded))
+		}
+	}
+
+	return fold.String(norm.NFD.String(b.String()))
+}
+
+func prototype(r rune) string {
+	if p, ok := confusables[r]; ok {
+		return p
+	}
+
+	return string(r)
+}
+
+// Confusable returns true if both strings look the same but are not
+// identical (ignoring case).
+func Confusable(a, b string) bool {
+	if cases.Fold().String(a) == cases.Fold().String(b) {
+		return false
+	}
+
+	return Skeleton(a) == Skeleton(b)
+}
+
+// SimilarDomain checks if the domain looks like (but is not) any of the
+// protected domains, either because it is confusable or because the edit
+// distance of their skeletons is at most maxDistance. It returns the
+// protected domain that the given domain resembles.
+func SimilarDomain(domain string, protected []string, maxDistance int) (string, bool) {
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return "", false
+	}
+
+	registrable := email.RegistrableDomain(domain)
+	skeleton := Skeleton(registrable)
+
+	for _, p := range protected {
+		p = strings.Trim(strings.ToLower(strings.TrimSpace(p)), ".")
+
+		// subdomains of a protected domain are legitimate
+		if domain == p || strings.HasSuffix(domain, "."+p) || registrable == p {
+			continue
+		}
+
+		other := Skeleton(email.RegistrableDomain(p))
+		if skeleton == other || smetrics.WagnerFischer(skeleton, other, 1, 1, 1) <= maxDistance {
+			return p, true
+		}
+	}
+
+	return "", false
+}
+
+// DisplayNameMismatch checks if the display name of an address mentions a
+// brand or domain that the address does not belong to, e.g.
+// "PayPal Service <info@random.example>". It returns the offending brand or
+// domain.
+func DisplayNameMismatch(name string, address string, brands []string) (string, bool) {
+	domain := address
+	if idx := strings.LastIndex(address, "@"); idx >= 0 {
+		domain = address[idx+1:]
+	}
+
+	domain = strings.ToLower(domain)
+	domainSkeleton := strings.ReplaceAll(Skeleton(domain), "-", "")
+
+	// display names like "support@paypal.com <info@random.example>"
+	for _, token := range strings.FieldsFunc(name, isAddressSeparator) {
+		token = strings.Trim(token, ".")
+		if idx := strings.LastIndex(token, "@"); idx >= 0 {
+			token = token[idx+1:]
+		}
+
+		if !strings.Contains(token, ".") || strings.ContainsFunc(token, unicode.IsSpace) {
+			continue
+		}
+
+		shown := email.RegistrableDomain(strings.ToLower(token))
+		if !strings.Contains(shown, ".") {
+			continue
+		}
+
+		if shown != email.RegistrableDomain(domain) {
+			return shown, true
+		}
+	}
+
+	// brand names, which spammers like to obfuscate ("P a y P a l", "PаyPаl")
+	words := strings.FieldsFunc(Skeleton(name), isWordSeparator)
+
+	for _, brand := range brands {
+		brandSkeleton := strings.Join(strings.FieldsFunc(Skeleton(brand), isWordSeparator), "")
+		if brandSkeleton == "" {
+			continue
+		}
+
+		if mentions(words, brandSkeleton) && !strings.Contains(domainSkeleton, brandSkeleton) {
+			return brand, true
+		}
+	}
+
+	return "", false
+}
+
+// mentions checks if any sequence of consecutive words forms the brand.
+func mentions(words []string, brand string) bool {
+	for i := range words {
+		joined := ""
+
+		for _, word := range words[i:] {
+			joined += word
+
+			if joined == brand {
+				return true
+			}
+
+			if len(joined) >= len(brand) {
+				break
+			}
+		}
+	}
+
+	return false
+}
+
+func isAddressSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`"'<>()[],;:`, r)
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package spoofing
+
+import (
+	"testing"
+)
+
+func TestConfusable(t *testing.T) {
+	testcases := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{a: "paypal.com", b: "paypal.com", expected: false},
+		{a: "PayPal.com", b: "paypal.com", expected: false},
+		{a: "paypa1.com", b: "paypal.com", expected: true},
+		{a: "pаypаl.com", b: "paypal.com", expected: true}, // Cyrillic a
+		{a: "g00gle.com", b: "google.com", expected: true},
+		{a: "rnicrosoft.com", b: "microsoft.com", expected: true},
+		{a: "Microsoft", b: "rnicrosoft", expected: true},
+		{a: "MICROSOFT.COM", b: "rnicrosoft.com", expected: true},
+		{a: "аmаzоn.com", b: "AMAZON.COM", expected: true}, // Cyrillic a and o
+		{a: "ｐａｙｐａｌ.com", b: "paypal.com", expected: true}, // fullwidth
+		{a: "paypal.de", b: "paypal.com", expected: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.a, func(t *testing.T) {
+			if result := Confusable(tc.a, tc.b); result != tc.expected {
+				t.Fatalf("Expected %v, got %v (skeletons %q and %q).", tc.expected, result, Skeleton(tc.a), Skeleton(tc.b))
+			}
+		})
+	}
+}
+
+func TestSimilarDomain(t *testing.T) {
+	protected := []string{"paypal.com", "sparkasse.de"}
+
+	testcases := []struct {
+		domain   string
+		expected string
+	}{
+		{domain: "paypal.com"},
+		{domain: "mail.paypal.com"},
+		{domain: "example.com"},
+		{domain: "paypa1.com", expected: "paypal.com"},
+		{domain: "mail.paypai.com", expected: "paypal.com"},
+		{domain: "spakasse.de", expected: "sparkasse.de"},
+		{domain: "sparkasse-online.de"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.domain, func(t *testing.T) {
+			similar, ok := SimilarDomain(tc.domain, protected, 1)
+			if ok != (tc.expected != "") || similar != tc.expected {
+				t.Fatalf("Expected %q, got %q.", tc.expected, similar)
+			}
+		})
+	}
+}
+
+func TestDisplayNameMismatch(t *testing.T) {
+	testcases := []struct {
+		name     string
+		address  string
+		expected string
+	}{
+		{name: "PayPal", address: "service@paypal.com"},
+		{name: "PayPal Service", address: "service@paypal.de"},
+		{name: "PayPal Service", address: "info@random.example", expected: "paypal"},
+		{name: "P a y P a l", address: "info@random.example", expected: "paypal"},
+		{name: "PаyPаl Support", address: "info@random.example", expected: "paypal"},
+		{name: "Microsoft Support", address: "info@random.example", expected: "microsoft"},
+		{name: "MICROSOFT", address: "info@random.example", expected: "microsoft"},
+		{name: "AMAZON", address: "info@random.example", expected: "amazon"},
+		{name: "Microsoft", address: "account-security-noreply@accountprotection.microsoft.com"},
+		{name: "UPS Delivery", address: "noreply@random.example", expected: "ups"},
+		{name: "Groups Newsletter", address: "noreply@random.example"},
+		{name: "Pete Appleton", address: "pete@example.com"},
+		{name: "Amazon.de", address: "noreply@amazon.de"},
+		{name: "service@paypal.com", address: "info@random.example", expected: "paypal.com"},
+		{name: "John Smith", address: "john@example.com"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			brand, ok := DisplayNameMismatch(tc.name, tc.address, DefaultBrands)
+			if ok != (tc.expected != "") || brand != tc.expected {
+				t.Fatalf("Expected %q, got %q.", tc.expected, brand)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package test
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSServer is a minimal in-process DNS server that answers A queries from
+// a fixed set of records and responds with NXDOMAIN for everything else.
+type DNSServer struct {
+	Addr string
+
+	conn    net.PacketConn
+	records map[string][]string
+	lock    sync.Mutex
+	queries []string
+}
+
+// NewDNSServer starts a server on a random local UDP port. Records map names
+// (without trailing dot) to IPv4 addresses. The server is stopped when the
+// test ends.
+func NewDNSServer(t *testing.T, records map[string][]string) *DNSServer {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start DNS server: %v", err)
+	}
+
+	server := &DNSServer{
+		Addr:    conn.LocalAddr().String(),
+		conn:    conn,
+		records: map[string][]string{},
+	}
+
+	for name, addrs := range records {
+		server.records[strings.ToLower(name)] = addrs
+	}
+
+	go server.serve()
+	t.Cleanup(func() { conn.Close() })
+
+	return server
+}
+
+// Queries returns all names that have been queried so far.
+func (s *DNSServer) Queries() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]string{}, s.queries...)
+}
+
+func (s *DNSServer) serve() {
+	buf := make([]byte, 512)
+
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		if response, err := s.respond(buf[:n]); err == nil {
+			_, _ = s.conn.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *DNSServer) respond(request []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+
+	header, err := parser.Start(request)
+	if err != nil {
+		return nil, err
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+
+	var answers []string
+	if question.Type == dnsmessage.TypeA {
+		s.lock.Lock()
+		s.queries = append(s.queries, name)
+		s.lock.Unlock()
+
+		answers = s.records[name]
+	}
+
+	rcode := dnsmessage.RCodeSuccess
+	if _, exists := s.records[name]; !exists {
+		rcode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		Authoritative:      true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	builder.EnableCompression()
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	for _, answer := range answers {
+		addr, err := netip.ParseAddr(answer)
+		if err != nil || !addr.Is4() {
+			continue
+		}
+
+		resource := dnsmessage.AResource{A: addr.As4()}
+		resourceHeader := dnsmessage.ResourceHeader{
+			Name:  question.Name,
+			Class: dnsmessage.ClassINET,
+			TTL:   60,
+		}
+
+		if err := builder.AResource(resourceHeader, resource); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package thread
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/email"
+	"go.xrstf.de/rudi-lda/pkg/fs"
+)
+
+// Entry is what the index remembers about a single delivered message.
+type Entry struct {
+	Folder    string    `json:"folder"`
+	Root      string    `json:"root,omitempty"`
+	Delivered time.Time `json:"delivered"`
+}
+
+// Thread describes how an incoming message relates to previously delivered
+// messages.
+type Thread struct {
+	// Root is the Message-ID of the first message in the conversation.
+	Root string
+	// ParentID is the Message-ID of the closest known ancestor.
+	ParentID string
+	// Parent is the index entry of the closest known ancestor, nil if
+	// no ancestor has been delivered before.
+	Parent *Entry
+	// Muted is true if the conversation has been muted.
+	Muted bool
+}
+
+type data struct {
+	Messages map[string]Entry `json:"messages"`
+	// Muted maps root Message-IDs to the last time the muted thread was
+	// active; mutes expire like messages do.
+	Muted map[string]time.Time `json:"muted,omitempty"`
+}
+
+// Index is a file-backed mapping of Message-IDs to the folders the messages
+// were delivered to. It is safe to be used by concurrent processes.
+type Index struct {
+	filename  string
+	retention time.Duration
+}
+
+func New(filename string, retention time.Duration) *Index {
+	return &Index{
+		filename:  filename,
+		retention: retention,
+	}
+}
+
+// Resolve finds the closest ancestor of the given message in the index.
+func (i *Index) Resolve(msg *email.Message) (*Thread, error) {
+	d, err := i.load()
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &Thread{
+		Root: rootID(msg),
+	}
+
+	// In-Reply-To usually names the direct parent, References is ordered
+	// from the oldest to the newest ancestor.
+	candidates := msg.GetInReplyTo()
+	references := msg.GetReferences()
+	for idx := len(references) - 1; idx >= 0; idx-- {
+		candidates = append(candidates, references[idx])
+	}
+
+	// expired entries are only removed on the next update, so they must
+	// be ignored here
+	now := time.Now()
+
+	for _, id := range candidates {
+		if entry, ok := d.Messages[id]; ok && !i.expired(entry.Delivered, now) {
+			thread.ParentID = id
+			thread.Parent = &entry
+
+			if entry.Root != "" {
+				thread.Root = entry.Root
+			}
+
+			break
+		}
+	}
+
+	if active, ok := d.Muted[thread.Root]; ok {
+		thread.Muted = !i.expired(active, now)
+	}
+
+	return thread, nil
+}
+
+// Record remembers the folder a message was delivered to. Expired entries
+// are removed at the same time.
+func (i *Index) Record(messageID string, entry Entry) error {
+	messageID = email.NormalizeMessageID(messageID)
+	if messageID == "" {
+		return nil
+	}
+
+	return i.update(func(d *data) {
+		d.Messages[messageID] = entry
+
+		// keep muted threads muted as long as they are active
+		if _, muted := d.Muted[entry.Root]; muted {
+			d.Muted[entry.Root] = entry.Delivered
+		}
+	})
+}
+
+// Mute marks the thread the given message belongs to as muted. Unknown
+// messages are assumed to be the root of their thread. The root
+// Message-ID is returned.
+func (i *Index) Mute(messageID string) (string, error) {
+	messageID = email.NormalizeMessageID(messageID)
+	if messageID == "" {
+		return "", errors.New("no Message-ID given")
+	}
+
+	root := messageID
+
+	err := i.update(func(d *data) {
+		if entry, ok := d.Messages[messageID]; ok && entry.Root != "" {
+			root = entry.Root
+		}
+
+		d.Muted[root] = time.Now()
+	})
+
+	return root, err
+}
+
+// Unmute removes the mute from the thread the given message belongs to.
+func (i *Index) Unmute(messageID string) (string, error) {
+	messageID = email.NormalizeMessageID(messageID)
+	if messageID == "" {
+		return "", errors.New("no Message-ID given")
+	}
+
+	root := messageID
+
+	err := i.update(func(d *data) {
+		if entry, ok := d.Messages[messageID]; ok && entry.Root != "" {
+			root = entry.Root
+		}
+
+		delete(d.Muted, root)
+	})
+
+	return root, err
+}
+
+func (i *Index) load() (*data, error) {
+	d := &data{
+		Messages: map[string]Entry{},
+		Muted:    map[string]time.Time{},
+	}
+
+	content, err := os.ReadFile(i.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+
+		return nil, fmt.Errorf("failed to read thread index: %w", err)
+	}
+
+	if err := json.Unmarshal(content, d); err != nil {
+		return nil, fmt.Errorf("failed to decode thread index: %w", err)
+	}
+
+	if d.Messages == nil {
+		d.Messages = map[string]Entry{}
+	}
+
+	if d.Muted == nil {
+		d.Muted = map[string]time.Time{}
+	}
+
+	return d, nil
+}
+
+func (i *Index) update(mutate func(d *data)) error {
+	unlock, err := fs.LockFile(i.filename + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock thread index: %w", err)
+	}
+	defer unlock()
+
+	d, err := i.load()
+	if err != nil {
+		return err
+	}
+
+	mutate(d)
+	i.expire(d)
+
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode thread index: %w", err)
+	}
+
+	return fs.WriteFileAtomic(i.filename, encoded)
+}
+
+func (i *Index) expire(d *data) {
+	now := time.Now()
+
+	for id, entry := range d.Messages {
+		if i.expired(entry.Delivered, now) {
+			delete(d.Messages, id)
+		}
+	}
+
+	for root, active := range d.Muted {
+		if i.expired(active, now) {
+			delete(d.Muted, root)
+		}
+	}
+}
+
+// expired returns true if the last activity is older than the retention.
+func (i *Index) expired(last time.Time, now time.Time) bool {
+	return i.retention > 0 && last.Before(now.Add(-i.retention))
+}
+
+// rootID guesses the root of a conversation purely based on the headers
+// of the given message.
+func rootID(msg *email.Message) string {
+	if references := msg.GetReferences(); len(references) > 0 {
+		return references[0]
+	}
+
+	if inReplyTo := msg.GetInReplyTo(); len(inReplyTo) > 0 {
+		return inReplyTo[0]
+	}
+
+	return msg.GetMessageID()
+}
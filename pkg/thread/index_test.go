@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: 2024 Christoph Mewes
+// SPDX-License-Identifier: MIT
+
+package thread
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.xrstf.de/rudi-lda/pkg/test"
+)
+
+func TestResolve(t *testing.T) {
+	index := New(filepath.Join(t.TempDir(), "threads.json"), 24*time.Hour)
+
+	if err := index.Record("<root@example.com>", Entry{Folder: "Projects.Foo", Root: "root@example.com", Delivered: time.Now()}); err != nil {
+		t.Fatalf("Failed to record message: %v", err)
+	}
+
+	if err := index.Record("old@example.com", Entry{Folder: "Old", Delivered: time.Now().Add(-48 * time.Hour)}); err != nil {
+		t.Fatalf("Failed to record message: %v", err)
+	}
+
+	testcases := []struct {
+		name           string
+		inReplyTo      string
+		references     string
+		expectedParent string
+		expectedFolder string
+		expectedRoot   string
+	}{
+		{
+			name:         "new conversation",
+			expectedRoot: "new@example.com",
+		},
+		{
+			name:           "direct reply",
+			inReplyTo:      "<root@example.com>",
+			expectedParent: "root@example.com",
+			expectedFolder: "Projects.Foo",
+			expectedRoot:   "root@example.com",
+		},
+		{
+			name:           "unknown parent, but known reference",
+			inReplyTo:      "<unknown@example.com>",
+			references:     "<root@example.com> <unknown@example.com>",
+			expectedParent: "root@example.com",
+			expectedFolder: "Projects.Foo",
+			expectedRoot:   "root@example.com",
+		},
+		{
+			name:         "expired parent",
+			inReplyTo:    "<old@example.com>",
+			expectedRoot: "old@example.com",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			builder := test.NewMessageBuilder().WithRawHeader("Message-Id", "<new@example.com>")
+			if testcase.inReplyTo != "" {
+				builder.WithRawHeader("In-Reply-To", testcase.inReplyTo)
+			}
+			if testcase.references != "" {
+				builder.WithRawHeader("References", testcase.references)
+			}
+
+			// recording another message triggers the expiry
+			if err := index.Record("other@example.com", Entry{Delivered: time.Now()}); err != nil {
+				t.Fatalf("Failed to record message: %v", err)
+			}
+
+			thr, err := index.Resolve(builder.Build())
+			if err != nil {
+				t.Fatalf("Failed to resolve thread: %v", err)
+			}
+
+			if thr.ParentID != testcase.expectedParent {
+				t.Errorf("Expected parent %q, got %q.", testcase.expectedParent, thr.ParentID)
+			}
+
+			if testcase.expectedFolder != "" && (thr.Parent == nil || thr.Parent.Folder != testcase.expectedFolder) {
+				t.Errorf("Expected folder %q, got %+v.", testcase.expectedFolder, thr.Parent)
+			}
+
+			if thr.Root != testcase.expectedRoot {
+				t.Errorf("Expected root %q, got %q.", testcase.expectedRoot, thr.Root)
+			}
+		})
+	}
+}
+
+func TestResolveIgnoresExpiredEntries(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "threads.json")
+
+	// entries are recorded with a long retention, so they are not removed
+	longLived := New(filename, 72*time.Hour)
+
+	if err := longLived.Record("<old@example.com>", Entry{Folder: "Old", Root: "old@example.com", Delivered: time.Now().Add(-48 * time.Hour)}); err != nil {
+		t.Fatalf("Failed to record message: %v", err)
+	}
+
+	if _, err := longLived.Mute("<old@example.com>"); err != nil {
+		t.Fatalf("Failed to mute thread: %v", err)
+	}
+
+	// the muted thread was last active two days ago
+	if err := longLived.Record("<old@example.com>", Entry{Folder: "Old", Root: "old@example.com", Delivered: time.Now().Add(-48 * time.Hour)}); err != nil {
+		t.Fatalf("Failed to record message: %v", err)
+	}
+
+	msg := test.NewMessageBuilder().
+		WithRawHeader("Message-Id", "<new@example.com>").
+		WithRawHeader("In-Reply-To", "<old@example.com>").
+		Build()
+
+	thr, err := longLived.Resolve(msg)
+	if err != nil {
+		t.Fatalf("Failed to resolve thread: %v", err)
+	}
+
+	if thr.Parent == nil || !thr.Muted {
+		t.Fatalf("Expected parent to be known and the thread to be muted, got %+v.", thr)
+	}
+
+	// without any update in between, a shorter retention must hide the entries
+	thr, err = New(filename, 24*time.Hour).Resolve(msg)
+	if err != nil {
+		t.Fatalf("Failed to resolve thread: %v", err)
+	}
+
+	if thr.Parent != nil || thr.Muted {
+		t.Fatalf("Expected expired parent and mute to be ignored, got %+v.", thr)
+	}
+}
+
+func TestMute(t *testing.T) {
+	index := New(filepath.Join(t.TempDir(), "threads.json"), 24*time.Hour)
+
+	if err := index.Record("reply@example.com", Entry{Folder: "Lists", Root: "root@example.com", Delivered: time.Now()}); err != nil {
+		t.Fatalf("Failed to record message: %v", err)
+	}
+
+	root, err := index.Mute("<reply@example.com>")
+	if err != nil {
+		t.Fatalf("Failed to mute thread: %v", err)
+	}
+
+	if root != "root@example.com" {
+		t.Fatalf("Expected root@example.com to be muted, but got %q.", root)
+	}
+
+	msg := test.NewMessageBuilder().
+		WithRawHeader("Message-Id", "<another@example.com>").
+		WithRawHeader("References", "<root@example.com> <unknown@example.com>").
+		Build()
+
+	thr, err := index.Resolve(msg)
+	if err != nil {
+		t.Fatalf("Failed to resolve thread: %v", err)
+	}
+
+	if !thr.Muted {
+		t.Fatal("Expected thread to be muted.")
+	}
+
+	if _, err := index.Unmute("root@example.com"); err != nil {
+		t.Fatalf("Failed to unmute thread: %v", err)
+	}
+
+	thr, err = index.Resolve(msg)
+	if err != nil {
+		t.Fatalf("Failed to resolve thread: %v", err)
+	}
+
+	if thr.Muted {
+		t.Fatal("Expected thread to not be muted anymore.")
+	}
+}